@@ -0,0 +1,19 @@
+package mux
+
+import "errors"
+
+/*
+   @Author: orbit-w
+   @File: error
+   @2026 10月 周日 22:10
+*/
+
+var (
+	ErrSessionClosed = errors.New("mux session closed")
+	ErrStreamClosed  = errors.New("stream closed") //本端已经关闭写方向
+	ErrStreamReset   = errors.New("stream reset")
+	ErrTooManyStream = errors.New("too many streams")
+	ErrFlowControl   = errors.New("stream flow control violated") //对端发送的数据超过接收窗口, 流被重置
+	ErrProtocol      = errors.New("mux protocol violated")        //对端打开的流 ID 不合法, Session 被关闭
+	ErrStreamIDLimit = errors.New("stream id exhausted")          //本端可用的流 ID 已用完
+)
@@ -0,0 +1,250 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: mux_test
+   @2026 10月 周日 22:50
+*/
+
+func Test_MuxEcho(t *testing.T) {
+	server := serveMux(t, nil, func(st *Stream) {
+		for {
+			in, err := st.Recv(context.Background())
+			if err != nil {
+				_ = st.Close()
+				return
+			}
+			_ = st.Send(in)
+		}
+	})
+	defer server.Stop()
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), nil)
+	defer sess.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			st, err := sess.OpenStream()
+			assert.NoError(t, err)
+			for j := 0; j < 10; j++ {
+				assert.NoError(t, st.Send([]byte(fmt.Sprintf("%d-%d", i, j))))
+			}
+			assert.NoError(t, st.Close())
+			for j := 0; j < 10; j++ {
+				in, err := st.Recv(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, fmt.Sprintf("%d-%d", i, j), string(in))
+			}
+			_, err = st.Recv(ctx)
+			assert.Equal(t, io.EOF, err)
+		}(i)
+	}
+	wg.Wait()
+
+	//两端都关闭后流被释放
+	assert.Eventually(t, func() bool {
+		return sess.NumStreams() == 0
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func Test_MuxFlowControl(t *testing.T) {
+	conf := DefaultConfig()
+	conf.InitialWindow = 1024
+	accepted := make(chan *Stream, 1)
+	server := serveMux(t, conf, func(st *Stream) {
+		accepted <- st
+		<-st.s.Done()
+	})
+	defer server.Stop()
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), conf)
+	defer sess.Close()
+
+	st, err := sess.OpenStream()
+	assert.NoError(t, err)
+	msg := make([]byte, 1024)
+	assert.NoError(t, st.Send(msg))
+
+	//窗口耗尽, 第二条消息阻塞直到对端消费
+	sent := make(chan error, 1)
+	go func() {
+		sent <- st.Send(msg)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("send not blocked by flow control")
+	case <-time.After(time.Millisecond * 200):
+	}
+
+	remote := <-accepted
+	in, err := remote.Recv(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, len(msg), len(in))
+	select {
+	case err = <-sent:
+		assert.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("send not resumed after window update")
+	}
+}
+
+func Test_MuxFlowControlViolated(t *testing.T) {
+	conf := DefaultConfig()
+	conf.InitialWindow = 1024
+	errCh := make(chan error, 1)
+	server := serveMux(t, conf, func(st *Stream) {
+		//等待对端发送超过窗口的数据后再读取
+		time.Sleep(time.Millisecond * 200)
+		_, err := st.Recv(context.Background())
+		errCh <- err
+	})
+	defer server.Stop()
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), conf)
+	defer sess.Close()
+
+	st, err := sess.OpenStream()
+	assert.NoError(t, err)
+	//绕过发送窗口直接写数据帧
+	msg := make([]byte, 512)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, sess.writeFrame(frameData, st.ID(), msg))
+	}
+
+	assert.Equal(t, ErrFlowControl, <-errCh)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err = st.Recv(ctx)
+	assert.Equal(t, ErrStreamReset, err)
+}
+
+func Test_MuxInvalidStreamID(t *testing.T) {
+	for name, open := range map[string]func(sess *Session) error{
+		//客户端打开服务端奇偶性的流 ID
+		"parity": func(sess *Session) error {
+			return sess.writeFrame(frameOpen, 2, nil)
+		},
+		//流 ID 没有递增
+		"backwards": func(sess *Session) error {
+			if _, err := sess.OpenStream(); err != nil {
+				return err
+			}
+			return sess.writeFrame(frameOpen, 1, nil)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := serveMux(t, nil, func(st *Stream) {
+				_, _ = st.Recv(context.Background())
+			})
+			defer server.Stop()
+
+			sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), nil)
+			defer sess.Close()
+			assert.NoError(t, open(sess))
+
+			//服务端以 ErrProtocol 关闭 Session 并断开连接
+			select {
+			case <-sess.Done():
+			case <-time.After(time.Second * 5):
+				t.Fatal("session not closed on invalid stream id")
+			}
+		})
+	}
+}
+
+func Test_MuxStreamIDExhausted(t *testing.T) {
+	server := serveMux(t, nil, func(st *Stream) {
+		_, _ = st.Recv(context.Background())
+	})
+	defer server.Stop()
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), nil)
+	defer sess.Close()
+
+	sess.mu.Lock()
+	sess.nextID = math.MaxUint32
+	sess.mu.Unlock()
+	st, err := sess.OpenStream()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(math.MaxUint32), st.ID())
+
+	//流 ID 回绕时不再打开新的流, 避免覆盖存活的流
+	_, err = sess.OpenStream()
+	assert.Equal(t, ErrStreamIDLimit, err)
+}
+
+func Test_MuxReset(t *testing.T) {
+	errCh := make(chan error, 1)
+	server := serveMux(t, nil, func(st *Stream) {
+		_, err := st.Recv(context.Background())
+		errCh <- err
+	})
+	defer server.Stop()
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), nil)
+	defer sess.Close()
+
+	st, err := sess.OpenStream()
+	assert.NoError(t, err)
+	assert.NoError(t, st.Reset())
+	assert.Equal(t, ErrStreamReset, <-errCh)
+	assert.Equal(t, ErrStreamReset, st.Send([]byte("after reset")))
+}
+
+func Test_MuxSessionClosed(t *testing.T) {
+	accepted := make(chan *Stream, 1)
+	server := serveMux(t, nil, func(st *Stream) {
+		accepted <- st
+	})
+
+	sess := NewClient(transport.DialWithOps(context.Background(), server.Addr()), nil)
+	defer sess.Close()
+	st, err := sess.OpenStream()
+	assert.NoError(t, err)
+	<-accepted
+
+	//GracefulStop 通知客户端关闭连接
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = server.GracefulStop(ctx)
+	}()
+	_, err = st.Recv(context.Background())
+	assert.Error(t, err)
+	<-sess.Done()
+	_, err = sess.OpenStream()
+	assert.Equal(t, sess.Err(), err)
+}
+
+func serveMux(t *testing.T, conf *Config, handle func(st *Stream)) transport.IServer {
+	server, err := transport.Serve("tcp", "127.0.0.1:0", func(conn transport.IConn) {
+		sess := NewServer(conn, conf)
+		for {
+			st, err := sess.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			go handle(st)
+		}
+	})
+	assert.NoError(t, err)
+	return server
+}
@@ -0,0 +1,273 @@
+package mux
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/orbit-w/meteor/bases/misc/utils"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/orbit-w/meteor/modules/net/transport"
+)
+
+/*
+   @Author: orbit-w
+   @File: session
+   @2026 10月 周日 22:10
+*/
+
+// 帧类型, 每个帧为一条 IConn 消息: type<int8> | stream id<uint32> | payload<bytes>
+const (
+	frameOpen   int8 = iota + 1 //打开流
+	frameData                   //流数据, payload 为一条消息
+	frameClose                  //关闭写方向, 对端读完已缓存的数据后 Recv 返回 io.EOF
+	frameReset                  //立即终止流, 双方丢弃未处理的数据
+	frameWindow                 //归还发送窗口, payload 为 delta<uint32>
+)
+
+const headSize = 5 //type<int8> | stream id<uint32>
+
+const (
+	DefaultInitialWindow = 256 << 10
+	DefaultAcceptBacklog = 1024
+)
+
+// Config 多路复用配置, 两端的 InitialWindow 需要一致
+type Config struct {
+	InitialWindow int //每个流的初始发送窗口(字节), 接收方每消费半个窗口归还一次
+	AcceptBacklog int //等待 AcceptStream 的流的最大数量, 超出时对端打开的流被重置
+	MaxStreams    int //最大并发流数量, 0 表示不限制
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		InitialWindow: DefaultInitialWindow,
+		AcceptBacklog: DefaultAcceptBacklog,
+	}
+}
+
+// Session 在一条 IConn 上复用多个双向的逻辑流.
+// 客户端打开的流 ID 为奇数, 服务端打开的流 ID 为偶数, 两端都可以打开流, 同一端打开的流 ID 严格递增.
+// 对端打开的流 ID 奇偶性错误或者没有递增时 Session 以 ErrProtocol 关闭.
+// Session 独占 IConn 的 Recv, IConn 关闭时所有流以关闭原因结束
+type Session struct {
+	conn   transport.IConn
+	conf   Config
+	accept chan *Stream
+	done   chan struct{}
+
+	mu        sync.Mutex
+	nextID    uint32
+	exhausted bool   //nextID 已经回绕, 不能再打开新的流
+	remoteID  uint32 //对端最近打开的流 ID
+	streams   map[uint32]*Stream
+	err       error //Session 关闭的原因
+}
+
+// NewClient 在客户端连接上创建 Session, conf 为空时使用 DefaultConfig
+func NewClient(conn transport.IConn, conf *Config) *Session {
+	return newSession(conn, conf, 1)
+}
+
+// NewServer 在服务端连接上创建 Session, conf 为空时使用 DefaultConfig
+func NewServer(conn transport.IConn, conf *Config) *Session {
+	return newSession(conn, conf, 2)
+}
+
+func newSession(conn transport.IConn, conf *Config, firstID uint32) *Session {
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	s := &Session{
+		conn:    conn,
+		conf:    *conf,
+		nextID:  firstID,
+		streams: make(map[uint32]*Stream),
+		done:    make(chan struct{}),
+	}
+	if s.conf.InitialWindow <= 0 {
+		s.conf.InitialWindow = DefaultInitialWindow
+	}
+	if s.conf.AcceptBacklog <= 0 {
+		s.conf.AcceptBacklog = DefaultAcceptBacklog
+	}
+	s.accept = make(chan *Stream, s.conf.AcceptBacklog)
+	go s.reader()
+	return s
+}
+
+// OpenStream 打开一个新的流, 对端通过 AcceptStream 获取
+func (s *Session) OpenStream() (*Stream, error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	if s.conf.MaxStreams > 0 && len(s.streams) >= s.conf.MaxStreams {
+		s.mu.Unlock()
+		return nil, ErrTooManyStream
+	}
+	if s.exhausted {
+		s.mu.Unlock()
+		return nil, ErrStreamIDLimit
+	}
+	id := s.nextID
+	s.nextID += 2
+	if s.nextID < id {
+		s.exhausted = true
+	}
+	st := newStream(s, id)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	if err := s.writeFrame(frameOpen, id, nil); err != nil {
+		s.remove(id)
+		return nil, err
+	}
+	return st, nil
+}
+
+// AcceptStream 阻塞等待对端打开的流, Session 关闭时返回关闭原因
+func (s *Session) AcceptStream(ctx context.Context) (*Stream, error) {
+	select {
+	case st := <-s.accept:
+		return st, nil
+	case <-s.done:
+		return nil, s.Err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NumStreams 当前存活的流数量
+func (s *Session) NumStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// Done 在 Session 关闭后关闭
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err 返回 Session 关闭的原因, 未关闭时返回 nil
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close 关闭底层连接, 所有流以 ErrSessionClosed 结束
+func (s *Session) Close() error {
+	return s.closeWithErr(ErrSessionClosed)
+}
+
+func (s *Session) closeWithErr(err error) error {
+	s.onClose(err)
+	return s.conn.Close()
+}
+
+func (s *Session) reader() {
+	defer utils.RecoverPanic()
+	for {
+		msg, err := transport.RecvMessage(context.Background(), s.conn)
+		if err != nil {
+			s.onClose(err)
+			return
+		}
+		if in := msg.Data; len(in) >= headSize {
+			s.onFrame(int8(in[0]), binary.BigEndian.Uint32(in[1:headSize]), in[headSize:])
+		}
+		msg.Release()
+	}
+}
+
+func (s *Session) onFrame(typ int8, id uint32, payload []byte) {
+	if typ == frameOpen {
+		s.onOpen(id)
+		return
+	}
+
+	s.mu.Lock()
+	st := s.streams[id]
+	s.mu.Unlock()
+	if st == nil {
+		return
+	}
+
+	switch typ {
+	case frameData:
+		st.onData(payload)
+	case frameClose:
+		st.onRemoteClose()
+	case frameReset:
+		st.onReset(ErrStreamReset)
+	case frameWindow:
+		if len(payload) >= 4 {
+			st.onWindow(int(binary.BigEndian.Uint32(payload)))
+		}
+	}
+}
+
+func (s *Session) onOpen(id uint32) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	//对端的流 ID 与本端奇偶性相反且严格递增
+	if id%2 == s.nextID%2 || id <= s.remoteID {
+		s.mu.Unlock()
+		_ = s.closeWithErr(ErrProtocol)
+		return
+	}
+	s.remoteID = id
+	if s.conf.MaxStreams > 0 && len(s.streams) >= s.conf.MaxStreams {
+		s.mu.Unlock()
+		_ = s.writeFrame(frameReset, id, nil)
+		return
+	}
+	st := newStream(s, id)
+	select {
+	case s.accept <- st:
+		s.streams[id] = st
+		s.mu.Unlock()
+	default:
+		s.mu.Unlock()
+		_ = s.writeFrame(frameReset, id, nil)
+	}
+}
+
+func (s *Session) writeFrame(typ int8, id uint32, payload []byte) error {
+	w := packet2.WriterP(headSize + len(payload))
+	w.WriteInt8(typ)
+	w.WriteUint32(id)
+	w.Write(payload)
+	err := s.conn.Send(w.Data())
+	packet2.Return(w)
+	return err
+}
+
+func (s *Session) remove(id uint32) {
+	s.mu.Lock()
+	delete(s.streams, id)
+	s.mu.Unlock()
+}
+
+func (s *Session) onClose(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	streams := s.streams
+	s.streams = make(map[uint32]*Stream)
+	s.mu.Unlock()
+
+	for _, st := range streams {
+		st.onReset(err)
+	}
+	close(s.done)
+}
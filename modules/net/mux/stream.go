@@ -0,0 +1,234 @@
+package mux
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/orbit-w/meteor/modules/net/transport"
+)
+
+/*
+   @Author: orbit-w
+   @File: stream
+   @2026 10月 周日 22:30
+*/
+
+var _ transport.IConn = (*Stream)(nil)
+
+// Stream Session 上的一个双向逻辑流, 实现了 transport.IConn, 保留消息边界.
+// 流量控制以消息为单位: 发送窗口耗尽时 Send 阻塞, 接收方 Recv 消费半个窗口后归还窗口,
+// 因此单个流缓存的未读数据最多超出窗口一条消息. 对端在未归还的窗口耗尽后继续发送时流被重置, Recv 返回 ErrFlowControl
+type Stream struct {
+	id uint32
+	s  *Session
+
+	mu           sync.Mutex
+	queue        [][]byte
+	window       int   //剩余发送窗口
+	buffered     int   //已接收但尚未消费的字节数
+	consumed     int   //已消费但尚未归还的接收窗口
+	readErr      error //对端关闭写方向后为 io.EOF
+	resetErr     error //流被重置或者 Session 关闭的原因
+	localClosed  bool
+	remoteClosed bool
+	readNotify   chan struct{}
+	sendNotify   chan struct{}
+}
+
+func newStream(s *Session, id uint32) *Stream {
+	return &Stream{
+		id:         id,
+		s:          s,
+		window:     s.conf.InitialWindow,
+		readNotify: make(chan struct{}, 1),
+		sendNotify: make(chan struct{}, 1),
+	}
+}
+
+func (st *Stream) ID() uint32 {
+	return st.id
+}
+
+// Send 发送一条消息, 发送窗口耗尽时阻塞直到对端归还窗口、流被重置或者 Session 关闭
+func (st *Stream) Send(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	st.mu.Lock()
+	for {
+		if st.resetErr != nil {
+			err := st.resetErr
+			st.mu.Unlock()
+			notify(st.sendNotify)
+			return err
+		}
+		if st.localClosed {
+			st.mu.Unlock()
+			notify(st.sendNotify)
+			return ErrStreamClosed
+		}
+		if st.window > 0 {
+			break
+		}
+		st.mu.Unlock()
+		<-st.sendNotify
+		st.mu.Lock()
+	}
+	st.window -= len(data)
+	more := st.window > 0
+	st.mu.Unlock()
+	if more {
+		//唤醒其它等待窗口的发送方
+		notify(st.sendNotify)
+	}
+	return st.s.writeFrame(frameData, st.id, data)
+}
+
+// Recv 阻塞接收一条消息, 对端关闭写方向且数据读完后返回 io.EOF, 流被重置时返回 ErrStreamReset
+func (st *Stream) Recv(ctx context.Context) ([]byte, error) {
+	for {
+		st.mu.Lock()
+		if st.resetErr != nil {
+			err := st.resetErr
+			st.mu.Unlock()
+			notify(st.readNotify)
+			return nil, err
+		}
+		if len(st.queue) > 0 {
+			in := st.queue[0]
+			st.queue[0] = nil
+			st.queue = st.queue[1:]
+			st.buffered -= len(in)
+			st.consumed += len(in)
+			var delta int
+			if st.consumed >= st.s.conf.InitialWindow/2 {
+				delta = st.consumed
+				st.consumed = 0
+			}
+			more := len(st.queue) > 0
+			st.mu.Unlock()
+			if more {
+				notify(st.readNotify)
+			}
+
+			if delta > 0 {
+				var payload [4]byte
+				binary.BigEndian.PutUint32(payload[:], uint32(delta))
+				_ = st.s.writeFrame(frameWindow, st.id, payload[:])
+			}
+			return in, nil
+		}
+		if st.readErr != nil {
+			err := st.readErr
+			st.mu.Unlock()
+			notify(st.readNotify)
+			return nil, err
+		}
+		st.mu.Unlock()
+
+		select {
+		case <-st.readNotify:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Close 关闭写方向并通知对端, 之后仍然可以 Recv 直到对端关闭; 两端都关闭后流被释放
+func (st *Stream) Close() error {
+	st.mu.Lock()
+	if st.localClosed || st.resetErr != nil {
+		st.mu.Unlock()
+		return nil
+	}
+	st.localClosed = true
+	both := st.remoteClosed
+	st.mu.Unlock()
+	notify(st.sendNotify)
+
+	err := st.s.writeFrame(frameClose, st.id, nil)
+	if both {
+		st.s.remove(st.id)
+	}
+	return err
+}
+
+// Reset 立即终止流并通知对端, 双方未处理的数据被丢弃
+func (st *Stream) Reset() error {
+	if !st.onReset(ErrStreamReset) {
+		return nil
+	}
+	return st.s.writeFrame(frameReset, st.id, nil)
+}
+
+// onData in 引用接收缓冲区, 拷贝后放入接收队列
+func (st *Stream) onData(in []byte) {
+	st.mu.Lock()
+	if st.remoteClosed || st.resetErr != nil {
+		st.mu.Unlock()
+		return
+	}
+	//对端只有在发送窗口未耗尽时才能发送, 未归还的字节数达到窗口后收到的数据违反流量控制
+	if st.buffered+st.consumed >= st.s.conf.InitialWindow {
+		st.mu.Unlock()
+		if st.onReset(ErrFlowControl) {
+			_ = st.s.writeFrame(frameReset, st.id, nil)
+		}
+		return
+	}
+	st.queue = append(st.queue, append([]byte(nil), in...))
+	st.buffered += len(in)
+	st.mu.Unlock()
+	notify(st.readNotify)
+}
+
+func (st *Stream) onRemoteClose() {
+	st.mu.Lock()
+	if st.remoteClosed || st.resetErr != nil {
+		st.mu.Unlock()
+		return
+	}
+	st.remoteClosed = true
+	st.readErr = io.EOF
+	both := st.localClosed
+	st.mu.Unlock()
+	notify(st.readNotify)
+
+	if both {
+		st.s.remove(st.id)
+	}
+}
+
+func (st *Stream) onWindow(delta int) {
+	st.mu.Lock()
+	st.window += delta
+	st.mu.Unlock()
+	notify(st.sendNotify)
+}
+
+// onReset 以 err 终止流并唤醒阻塞的 Send 与 Recv, 返回是否由本次调用终止
+func (st *Stream) onReset(err error) bool {
+	st.mu.Lock()
+	if st.resetErr != nil {
+		st.mu.Unlock()
+		return false
+	}
+	st.resetErr = err
+	st.queue = nil
+	st.buffered = 0
+	st.mu.Unlock()
+	notify(st.readNotify)
+	notify(st.sendNotify)
+	st.s.remove(st.id)
+	return true
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
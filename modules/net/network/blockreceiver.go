@@ -1,9 +1,31 @@
 package network
 
-import "context"
+import (
+	"context"
+
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+// Message 接收到的一条消息, Data 引用 packet.Buffer 中的数据, 同一帧内的消息共享一个 Buffer.
+// 使用完毕后调用 Release 归还缓冲区, 之后不能再访问 Data; 每条消息只能 Release 一次
+type Message struct {
+	Data []byte
+	buf  *packet2.Buffer
+}
+
+// NewMessage 创建引用 buf 的消息, 调用方需要预先为该消息持有 buf 的一个引用. buf 为 nil 时 Release 什么也不做
+func NewMessage(data []byte, buf *packet2.Buffer) Message {
+	return Message{Data: data, buf: buf}
+}
+
+// Release 释放消息持有的缓冲区引用
+func (m Message) Release() {
+	m.buf.Release()
+}
 
 type recvMsg struct {
 	in  []byte
+	buf *packet2.Buffer
 	err error
 }
 
@@ -21,6 +43,7 @@ func NewBlockReceiver() *BlockReceiver {
 	}
 }
 
+// Recv 返回的 in 由调用方长期持有: 消息拷贝到新分配的内存后立即归还缓冲区, 避免拷贝时使用 RecvMessage
 func (r *BlockReceiver) Recv(ctx context.Context) (in []byte, err error) {
 	select {
 	case msg, ok := <-r.buf.get():
@@ -31,12 +54,34 @@ func (r *BlockReceiver) Recv(ctx context.Context) (in []byte, err error) {
 			return msg.in, msg.err
 		}
 		r.buf.load()
-		return msg.in, nil
+		if msg.buf == nil {
+			return msg.in, nil
+		}
+		in = append([]byte(nil), msg.in...)
+		msg.buf.Release()
+		return in, nil
 	case <-ctx.Done():
 		return nil, ErrCanceled
 	}
 }
 
+// RecvMessage 与 Recv 相同, 返回的消息使用完毕后需要调用 Message.Release
+func (r *BlockReceiver) RecvMessage(ctx context.Context) (Message, error) {
+	select {
+	case msg, ok := <-r.buf.get():
+		if !ok {
+			return Message{}, ErrCanceled
+		}
+		if msg.Err() != nil {
+			return Message{}, msg.err
+		}
+		r.buf.load()
+		return NewMessage(msg.in, msg.buf), nil
+	case <-ctx.Done():
+		return Message{}, ErrCanceled
+	}
+}
+
 func (r *BlockReceiver) Put(in []byte, err error) {
 	_ = r.buf.put(recvMsg{
 		in:  in,
@@ -44,6 +89,13 @@ func (r *BlockReceiver) Put(in []byte, err error) {
 	})
 }
 
+// PutMessage 投递一条消息, 接收方关闭后投递失败时释放消息
+func (r *BlockReceiver) PutMessage(msg Message) {
+	if err := r.buf.put(recvMsg{in: msg.Data, buf: msg.buf}); err != nil {
+		msg.Release()
+	}
+}
+
 func (r *BlockReceiver) OnClose(err error) {
 	_ = r.buf.put(recvMsg{
 		err: err,
@@ -0,0 +1,214 @@
+package network
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: chaos
+   @2026 10月 周日 21:05
+*/
+
+// ChaosFaults 一个阶段内注入的故障, 概率均以一次读写为单位
+type ChaosFaults struct {
+	Delay            time.Duration //每次写出前等待的时长
+	Jitter           time.Duration //写出前额外等待 [0, Jitter) 内的随机时长
+	Bandwidth        int           //每个方向每秒最多传输的字节数, 0 表示不限制
+	PartialWriteRate float64       //一次写入拆分为两次底层写入的概率, 对端分两次读到数据
+	TruncateRate     float64       //一次读写只传输前一部分后重置连接的概率
+	ResetRate        float64       //一次读写时重置连接的概率
+}
+
+// ChaosStage 连接建立 At 之后生效的故障, 直到下一个阶段开始
+type ChaosStage struct {
+	At time.Duration
+	ChaosFaults
+}
+
+// ChaosOptions 故障注入配置, Stages 按 At 升序排列, 第一个阶段开始之前不注入故障.
+// 读与写各自使用由 Seed 派生的随机数序列, 相同的 Seed 与读写序列得到相同的故障
+type ChaosOptions struct {
+	Seed   int64
+	Stages []ChaosStage
+}
+
+// ChaosDirection 读写方向
+type ChaosDirection int8
+
+const (
+	ChaosInbound  ChaosDirection = iota //读
+	ChaosOutbound                       //写
+)
+
+// ChaosAction 一次读写注入的故障
+type ChaosAction struct {
+	Wait     time.Duration //读写前后等待的时长: 时延、抖动与带宽限制
+	Reset    bool          //重置连接
+	Truncate int           //大于 0 时只传输前 Truncate 个字节
+	Split    int           //大于 0 时写入在 Split 处拆分为两次
+}
+
+// ChaosInjector 按 ChaosOptions 的阶段决定每次读写注入的故障, 阶段从创建时开始计时. 线程安全
+type ChaosInjector struct {
+	mu     sync.Mutex
+	stages []ChaosStage
+	start  time.Time
+	rand   [2]*rand.Rand
+	next   [2]time.Time //带宽限制下每个方向下一次可以开始传输的时间
+}
+
+func NewChaosInjector(op *ChaosOptions) *ChaosInjector {
+	return &ChaosInjector{
+		stages: op.Stages,
+		start:  time.Now(),
+		rand: [2]*rand.Rand{
+			rand.New(rand.NewSource(op.Seed)),
+			rand.New(rand.NewSource(op.Seed + 1)),
+		},
+	}
+}
+
+// Faults 返回当前阶段的故障, 第一个阶段开始之前返回 nil
+func (ci *ChaosInjector) Faults() *ChaosFaults {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+	return ci.faults(time.Now())
+}
+
+// Next 决定在 dir 方向传输 n 个字节时注入的故障, 只有写入会被拆分
+func (ci *ChaosInjector) Next(dir ChaosDirection, n int) ChaosAction {
+	ci.mu.Lock()
+	defer ci.mu.Unlock()
+
+	var action ChaosAction
+	now := time.Now()
+	faults := ci.faults(now)
+	if faults == nil {
+		return action
+	}
+
+	r := ci.rand[dir]
+	if faults.ResetRate > 0 && r.Float64() < faults.ResetRate {
+		action.Reset = true
+		return action
+	}
+	if n > 1 && faults.TruncateRate > 0 && r.Float64() < faults.TruncateRate {
+		action.Truncate = 1 + r.Intn(n-1)
+		n = action.Truncate
+	}
+	if dir == ChaosOutbound && action.Truncate == 0 && n > 1 &&
+		faults.PartialWriteRate > 0 && r.Float64() < faults.PartialWriteRate {
+		action.Split = 1 + r.Intn(n-1)
+	}
+
+	if dir == ChaosOutbound {
+		action.Wait = faults.Delay
+		if faults.Jitter > 0 {
+			action.Wait += time.Duration(r.Int63n(int64(faults.Jitter)))
+		}
+	}
+	if faults.Bandwidth > 0 {
+		begin := ci.next[dir]
+		if begin.Before(now) {
+			begin = now
+		}
+		ci.next[dir] = begin.Add(time.Duration(n) * time.Second / time.Duration(faults.Bandwidth))
+		action.Wait += ci.next[dir].Sub(now)
+	}
+	return action
+}
+
+func (ci *ChaosInjector) faults(now time.Time) *ChaosFaults {
+	elapsed := now.Sub(ci.start)
+	var faults *ChaosFaults
+	for i := range ci.stages {
+		if ci.stages[i].At > elapsed {
+			break
+		}
+		faults = &ci.stages[i].ChaosFaults
+	}
+	return faults
+}
+
+// ChaosConn 注入故障的 net.Conn: 写出前等待时延、抖动与带宽限制, 读到数据后等待带宽限制,
+// 按概率拆分写入、截断读写或者重置连接. 重置时关闭底层连接, 读写返回 ErrChaosReset, 截断的写入返回 ErrChaosTruncated
+type ChaosConn struct {
+	net.Conn
+	injector *ChaosInjector
+	wmu      sync.Mutex //与 net.Conn.Write 一样保证一次 Write 的数据不会与其他协程的写入交错
+}
+
+// WrapChaos 使用 op 为 conn 注入故障, 每条连接独立计时
+func WrapChaos(conn net.Conn, op *ChaosOptions) *ChaosConn {
+	return &ChaosConn{
+		Conn:     conn,
+		injector: NewChaosInjector(op),
+	}
+}
+
+// Injector 返回连接的故障注入器
+func (c *ChaosConn) Injector() *ChaosInjector {
+	return c.injector
+}
+
+func (c *ChaosConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n == 0 {
+		return n, err
+	}
+
+	action := c.injector.Next(ChaosInbound, n)
+	if action.Reset {
+		_ = c.Conn.Close()
+		return 0, ErrChaosReset
+	}
+	if action.Truncate > 0 {
+		//读到的数据只保留前一部分, 之后的数据丢失
+		_ = c.Conn.Close()
+		return action.Truncate, err
+	}
+	sleep(action.Wait)
+	return n, err
+}
+
+func (c *ChaosConn) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return c.Conn.Write(b)
+	}
+
+	action := c.injector.Next(ChaosOutbound, len(b))
+	if action.Reset {
+		_ = c.Conn.Close()
+		return 0, ErrChaosReset
+	}
+	sleep(action.Wait)
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if action.Truncate > 0 {
+		n, _ := c.Conn.Write(b[:action.Truncate])
+		_ = c.Conn.Close()
+		return n, ErrChaosTruncated
+	}
+	if action.Split > 0 {
+		n, err := c.Conn.Write(b[:action.Split])
+		if err != nil {
+			return n, err
+		}
+		//让出时间片, 对端尽可能分两次读到数据
+		time.Sleep(time.Millisecond)
+		m, err := c.Conn.Write(b[action.Split:])
+		return n + m, err
+	}
+	return c.Conn.Write(b)
+}
+
+func sleep(d time.Duration) {
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
@@ -0,0 +1,187 @@
+package network
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: chaos_test
+   @2026 10月 周日 21:55
+*/
+
+func TestChaosInjector_Deterministic(t *testing.T) {
+	op := &ChaosOptions{
+		Seed: 42,
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{
+			Jitter:           time.Millisecond,
+			PartialWriteRate: 0.3,
+			TruncateRate:     0.1,
+			ResetRate:        0.05,
+		}}},
+	}
+	run := func() []ChaosAction {
+		ci := NewChaosInjector(op)
+		actions := make([]ChaosAction, 0, 200)
+		for i := 0; i < 100; i++ {
+			actions = append(actions, ci.Next(ChaosOutbound, 100), ci.Next(ChaosInbound, 100))
+		}
+		return actions
+	}
+
+	first := run()
+	assert.Equal(t, first, run())
+
+	var resets, truncates, splits int
+	for _, action := range first {
+		switch {
+		case action.Reset:
+			resets++
+		case action.Truncate > 0:
+			assert.Less(t, action.Truncate, 100)
+			truncates++
+		case action.Split > 0:
+			splits++
+		}
+	}
+	assert.Greater(t, resets, 0)
+	assert.Greater(t, truncates, 0)
+	assert.Greater(t, splits, 0)
+}
+
+func TestChaosInjector_Stages(t *testing.T) {
+	ci := NewChaosInjector(&ChaosOptions{
+		Stages: []ChaosStage{
+			{At: time.Millisecond * 50, ChaosFaults: ChaosFaults{Delay: time.Millisecond * 10}},
+			{At: time.Millisecond * 100, ChaosFaults: ChaosFaults{ResetRate: 1}},
+		},
+	})
+	assert.Nil(t, ci.Faults())
+	assert.Equal(t, ChaosAction{}, ci.Next(ChaosOutbound, 10))
+
+	time.Sleep(time.Millisecond * 60)
+	assert.Equal(t, ChaosAction{Wait: time.Millisecond * 10}, ci.Next(ChaosOutbound, 10))
+	//时延只作用于写
+	assert.Equal(t, ChaosAction{}, ci.Next(ChaosInbound, 10))
+
+	time.Sleep(time.Millisecond * 50)
+	assert.True(t, ci.Next(ChaosInbound, 10).Reset)
+}
+
+func TestChaosInjector_Bandwidth(t *testing.T) {
+	ci := NewChaosInjector(&ChaosOptions{
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{Bandwidth: 1000}}},
+	})
+	first := ci.Next(ChaosOutbound, 100).Wait
+	second := ci.Next(ChaosOutbound, 100).Wait
+	assert.InDelta(t, float64(time.Millisecond*100), float64(first), float64(time.Millisecond*5))
+	assert.InDelta(t, float64(time.Millisecond*200), float64(second), float64(time.Millisecond*5))
+	//两个方向分别限制
+	assert.InDelta(t, float64(time.Millisecond*100), float64(ci.Next(ChaosInbound, 100).Wait), float64(time.Millisecond*5))
+}
+
+// readAll 读取 conn 直到出错, 返回每次 Read 读到的数据
+func readAll(conn net.Conn) chan []string {
+	result := make(chan []string, 1)
+	go func() {
+		var reads []string
+		buf := make([]byte, 64)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				reads = append(reads, string(buf[:n]))
+			}
+			if err != nil {
+				result <- reads
+				return
+			}
+		}
+	}()
+	return result
+}
+
+func TestChaosConn_PartialWrite(t *testing.T) {
+	client, server := net.Pipe()
+	conn := WrapChaos(client, &ChaosOptions{
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{PartialWriteRate: 1}}},
+	})
+	result := readAll(server)
+
+	n, err := conn.Write([]byte("hello world"))
+	assert.NoError(t, err)
+	assert.Equal(t, 11, n)
+	_ = conn.Close()
+
+	reads := <-result
+	assert.Len(t, reads, 2)
+	assert.Equal(t, "hello world", reads[0]+reads[1])
+}
+
+func TestChaosConn_Truncate(t *testing.T) {
+	client, server := net.Pipe()
+	conn := WrapChaos(client, &ChaosOptions{
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{TruncateRate: 1}}},
+	})
+	result := readAll(server)
+
+	n, err := conn.Write([]byte("hello world"))
+	assert.ErrorIs(t, err, ErrChaosTruncated)
+	assert.Less(t, n, 11)
+
+	reads := <-result
+	assert.Len(t, reads, 1)
+	assert.Equal(t, "hello world"[:n], reads[0])
+}
+
+func TestChaosConn_Reset(t *testing.T) {
+	client, server := net.Pipe()
+	conn := WrapChaos(client, &ChaosOptions{
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{ResetRate: 1}}},
+	})
+	result := readAll(server)
+
+	_, err := conn.Write([]byte("hello"))
+	assert.ErrorIs(t, err, ErrChaosReset)
+	assert.Empty(t, <-result)
+
+	_, err = client.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+// 拆分写入的两段之间不会插入其他协程的写入
+func TestChaosConn_PartialWriteConcurrent(t *testing.T) {
+	client, server := net.Pipe()
+	conn := WrapChaos(client, &ChaosOptions{
+		Stages: []ChaosStage{{ChaosFaults: ChaosFaults{PartialWriteRate: 1}}},
+	})
+	result := readAll(server)
+
+	const writers, count = 4, 20
+	wg := sync.WaitGroup{}
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(c byte) {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				_, err := conn.Write(bytes.Repeat([]byte{c}, 8))
+				assert.NoError(t, err)
+			}
+		}('a' + byte(i))
+	}
+	wg.Wait()
+	_ = conn.Close()
+
+	stream := strings.Join(<-result, "")
+	assert.Len(t, stream, writers*count*8)
+	for i := 0; i+8 <= len(stream); i += 8 {
+		assert.Equal(t, strings.Repeat(stream[i:i+1], 8), stream[i:i+8])
+	}
+}
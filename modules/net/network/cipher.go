@@ -0,0 +1,193 @@
+package network
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+/*
+   @Author: orbit-w
+   @File: cipher
+   @2026 10月 周日 20:10
+*/
+
+// CipherSuite 应用层加密套件
+type CipherSuite uint8
+
+const (
+	CipherAES256GCM        CipherSuite = iota + 1 //服务端 CPU 支持 AES-NI 时首选
+	CipherChaCha20Poly1305                        //无 AES 硬件加速的移动设备首选
+)
+
+const (
+	flagEncrypted = 0x80 //消息头压缩标识字节的最高位, 表示消息体已加密
+	keySize       = 32
+	publicKeySize = 32 //X25519 公钥长度
+)
+
+// EncryptionOptions 应用层加密配置: 连接建立后先通过 TypeMessageKeyExchange 帧完成 X25519 密钥交换,
+// 之后所有帧(包括心跳、关闭通知等控制帧)的消息体使用 AEAD 加密, 消息头作为附加数据参与认证.
+// 只适用于可靠有序的流式传输(TCP, KCP, WebSocket)
+type EncryptionOptions struct {
+	Suite         CipherSuite      //客户端提议的加密套件, 服务端接受任意受支持的套件
+	PrivateKey    *ecdh.PrivateKey //服务端的静态 X25519 私钥, 为空时每条连接临时生成
+	PeerPublicKey []byte           //客户端预置的服务端公钥, 不为空时校验以防止中间人攻击
+}
+
+// Cipher 一条连接双向的 AEAD 状态, 以递增的序号作为 nonce, 因此不需要在消息中携带 nonce,
+// 且天然防重放与乱序. Seal 的调用需要互斥且与帧写出连接的顺序一致, Open 只能在接收协程中调用
+type Cipher struct {
+	seal    cipher.AEAD
+	open    cipher.AEAD
+	sendSeq uint64
+	recvSeq uint64
+	nonce   [12]byte
+}
+
+func NewCipher(suite CipherSuite, sendKey, recvKey []byte) (*Cipher, error) {
+	seal, err := newAEAD(suite, sendKey)
+	if err != nil {
+		return nil, err
+	}
+	open, err := newAEAD(suite, recvKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{seal: seal, open: open}, nil
+}
+
+// Seal 加密 plaintext 并追加到 dst 后返回
+func (c *Cipher) Seal(dst, plaintext, aad []byte) []byte {
+	nonce := c.nextNonce(&c.sendSeq)
+	return c.seal.Seal(dst, nonce, plaintext, aad)
+}
+
+// Open 原地解密 ciphertext, 返回的明文引用 ciphertext 的内存
+func (c *Cipher) Open(ciphertext, aad []byte) ([]byte, error) {
+	var nonce [12]byte
+	binary.BigEndian.PutUint64(nonce[4:], c.recvSeq)
+	plaintext, err := c.open.Open(ciphertext[:0], nonce[:], ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	c.recvSeq++
+	return plaintext, nil
+}
+
+// Overhead 每条加密消息增加的字节数
+func (c *Cipher) Overhead() int {
+	return c.seal.Overhead()
+}
+
+func (c *Cipher) nextNonce(seq *uint64) []byte {
+	binary.BigEndian.PutUint64(c.nonce[4:], *seq)
+	*seq++
+	return c.nonce[:]
+}
+
+func newAEAD(suite CipherSuite, key []byte) (cipher.AEAD, error) {
+	switch suite {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, ErrUnsupportedCipher
+	}
+}
+
+// KeyExchange 一次 X25519 密钥交换
+type KeyExchange struct {
+	suite CipherSuite
+	priv  *ecdh.PrivateKey
+}
+
+// NewKeyExchange priv 为空时临时生成密钥对
+func NewKeyExchange(suite CipherSuite, priv *ecdh.PrivateKey) (*KeyExchange, error) {
+	if suite != CipherAES256GCM && suite != CipherChaCha20Poly1305 {
+		return nil, ErrUnsupportedCipher
+	}
+	if priv == nil {
+		var err error
+		if priv, err = ecdh.X25519().GenerateKey(rand.Reader); err != nil {
+			return nil, err
+		}
+	}
+	return &KeyExchange{suite: suite, priv: priv}, nil
+}
+
+func (k *KeyExchange) PublicKey() []byte {
+	return k.priv.PublicKey().Bytes()
+}
+
+// Encode 密钥交换帧的消息体: suite<uint8> | publicKey<32 bytes>
+func (k *KeyExchange) Encode() []byte {
+	return append([]byte{byte(k.suite)}, k.PublicKey()...)
+}
+
+// ClientCipher 客户端根据服务端公钥生成 Cipher
+func (k *KeyExchange) ClientCipher(serverPub []byte) (*Cipher, error) {
+	c2s, s2c, err := k.deriveKeys(k.PublicKey(), serverPub, serverPub)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(k.suite, c2s, s2c)
+}
+
+// ServerCipher 服务端根据客户端公钥生成 Cipher
+func (k *KeyExchange) ServerCipher(clientPub []byte) (*Cipher, error) {
+	c2s, s2c, err := k.deriveKeys(clientPub, k.PublicKey(), clientPub)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(k.suite, s2c, c2s)
+}
+
+// deriveKeys 使用 HKDF-SHA256 从共享密钥派生双向的会话密钥, 双方公钥作为 salt
+func (k *KeyExchange) deriveKeys(clientPub, serverPub, peerPub []byte) (c2s, s2c []byte, err error) {
+	peer, err := ecdh.X25519().NewPublicKey(peerPub)
+	if err != nil {
+		return nil, nil, ErrKeyExchangeFailed
+	}
+	secret, err := k.priv.ECDH(peer)
+	if err != nil {
+		return nil, nil, ErrKeyExchangeFailed
+	}
+
+	salt := append(append([]byte{}, clientPub...), serverPub...)
+	r := hkdf.New(sha256.New, secret, salt, []byte("meteor key exchange"))
+	keys := make([]byte, keySize*2)
+	if _, err = io.ReadFull(r, keys); err != nil {
+		return nil, nil, err
+	}
+	return keys[:keySize], keys[keySize:], nil
+}
+
+// DecodeKeyExchange 解析密钥交换帧的消息体
+func DecodeKeyExchange(body []byte) (CipherSuite, []byte, error) {
+	if len(body) != 1+publicKeySize {
+		return 0, nil, ErrKeyExchangeFailed
+	}
+	return CipherSuite(body[0]), body[1:], nil
+}
+
+// VerifyPeerPublicKey 校验对端公钥, expected 为空时不校验
+func VerifyPeerPublicKey(expected, actual []byte) error {
+	if len(expected) > 0 && !bytes.Equal(expected, actual) {
+		return ErrPeerKeyMismatch
+	}
+	return nil
+}
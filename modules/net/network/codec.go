@@ -16,62 +16,133 @@ import (
 */
 
 const (
-	gzipSize = 1
-	headSize = 1
+	compressSize = 1
+	headSize     = 1
 )
 
 // Codec 通用流式数据编解码器
-// 通用消息编码协议 body: size<int32> | gzipped<bool> | type<int8> | length<uint32> | data<bytes> | length<uint32> | data<bytes> | ...
+// 通用消息编码协议 body: size<int32> | compressor<uint8> | type<int8> | length<uint32> | data<bytes> | length<uint32> | data<bytes> | ...
+// compressor 为压缩算法 ID(CompressorID), 0 表示未压缩
 type Codec struct {
-	isGzip          bool //压缩标识符（建议超过100byte消息进行压缩）
+	cipher          *Cipher    //为 nil 时不加密
+	compressor      Compressor //为 nil 时不压缩
+	threshold       int        //长度小于该值的消息不压缩
 	maxIncomingSize uint32
 	readTimeout     time.Duration
 }
 
+// NewCodec _isGzip 为 true 时使用 gzip 压缩长度不小于 CompressThreshold 的消息
 func NewCodec(max uint32, _isGzip bool, _readTimeout time.Duration) *Codec {
+	id := CompressNone
+	if _isGzip {
+		id = CompressGzip
+	}
+	return NewCodecWithCompressor(max, id, CompressThreshold, _readTimeout)
+}
+
+// NewCodecWithCompressor 使用 id 对应的压缩算法压缩长度不小于 threshold 的消息, threshold 小于 0 时压缩所有消息;
+// 解码时根据消息头中的压缩算法 ID 解压, 与编码端的配置无关
+func NewCodecWithCompressor(max uint32, id CompressorID, threshold int, _readTimeout time.Duration) *Codec {
 	if _readTimeout == 0 {
 		_readTimeout = ReadTimeout
 	}
 
 	return &Codec{
-		isGzip:          _isGzip,
+		compressor:      GetCompressor(id),
+		threshold:       threshold,
 		maxIncomingSize: max,
 		readTimeout:     _readTimeout,
 	}
 }
 
-// Encode 消息编码协议 body: size<int32> | gzipped<bool> | type<int8> | body<bytes>
+// SetCipher 设置连接协商出的加密状态, 之后编码的所有帧均被加密, 且只接受加密的帧.
+// Codec 不再能在多条连接间共享, 调用方需要保证帧的编码顺序与写出连接的顺序一致
+func (c *Codec) SetCipher(cipher *Cipher) {
+	c.cipher = cipher
+}
+
+// Encode 消息编码协议 body: size<int32> | compressor<uint8> | type<int8> | body<bytes>
+// 压缩后长度没有减少时发送原始数据; 设置了 Cipher 时先压缩再加密
 func (c *Codec) Encode(data []byte, h int8) (packet2.IPacket, error) {
-	if c.isGzip {
-		compressed, err := EncodeGzip(data)
-		if err != nil {
-			return nil, EncodeGzipFailed(err)
-		}
-		data = compressed
+	data, id, err := c.compress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.cipher != nil {
+		return c.encodeSealed(data, h, byte(id)|flagEncrypted), nil
+	}
+	return c.encode(data, h, id), nil
+}
+
+// EncodeVector 与 Encode 相同, 但不拷贝消息体: 帧头写入 header(长度不小于 FrameHeadLen), 返回帧体.
+// 未压缩且未加密时帧体即为 data, 可以与 header 一起通过 net.Buffers 写出
+func (c *Codec) EncodeVector(header, data []byte, h int8) ([]byte, error) {
+	data, id, err := c.compress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	flag := byte(id)
+	if c.cipher != nil {
+		flag |= flagEncrypted
+		aad := [2]byte{flag, byte(h)}
+		data = c.cipher.Seal(nil, data, aad[:])
 	}
+	binary.BigEndian.PutUint32(header, uint32(compressSize+headSize+len(data)))
+	header[HeadLen] = flag
+	header[HeadLen+compressSize] = byte(h)
+	return data, nil
+}
+
+// compress 压缩长度不小于阈值的消息, 压缩后长度没有减少时返回原始数据
+func (c *Codec) compress(data []byte) ([]byte, CompressorID, error) {
+	if c.compressor == nil || len(data) == 0 || len(data) < c.threshold {
+		return data, CompressNone, nil
+	}
+	compressed, err := c.compressor.Compress(data)
+	if err != nil {
+		return nil, CompressNone, EncodeCompressFailed(c.compressor.Name(), err)
+	}
+	if len(compressed) < len(data) {
+		return compressed, c.compressor.ID(), nil
+	}
+	return data, CompressNone, nil
+}
+
+// EncodeBody 编码消息, 不压缩; 设置了 Cipher 时加密, 关闭通知、心跳等控制帧同样需要认证, 防止被伪造
+func (c *Codec) EncodeBody(data []byte, h int8) packet2.IPacket {
+	if c.cipher != nil {
+		return c.encodeSealed(data, h, flagEncrypted)
+	}
+	return c.encode(data, h, CompressNone)
+}
 
-	l := gzipSize + headSize + len(data)
+func (c *Codec) encodeSealed(data []byte, h int8, flag byte) packet2.IPacket {
+	aad := [2]byte{flag, byte(h)}
+	l := compressSize + headSize + len(data) + c.cipher.Overhead()
 	w := packet2.WriterP(4 + l)
 	w.WriteInt32(int32(l))
-	w.WriteBool(c.isGzip)
+	w.WriteUint8(flag)
 	w.WriteInt8(h)
-	w.Write(data)
-	return w, nil
+	w.Write(c.cipher.Seal(nil, data, aad[:]))
+	return w
 }
 
-func (c *Codec) EncodeBody(data []byte, h int8) packet2.IPacket {
-	l := gzipSize + headSize + len(data)
+func (c *Codec) encode(data []byte, h int8, id CompressorID) packet2.IPacket {
+	l := compressSize + headSize + len(data)
 	w := packet2.WriterP(4 + l)
 	w.WriteInt32(int32(l))
-	w.WriteBool(c.isGzip)
+	w.WriteUint8(uint8(id))
 	w.WriteInt8(h)
 	w.Write(data)
 	return w
 }
 
-// BlockDecodeBody 消息解码协议 body: size<int32> | gzipped<bool> | type<int8> | body<bytes>
+// BlockDecodeBody 消息解码协议 body: size<int32> | compressor<uint8> | type<int8> | body<bytes>
 // Returns the decoded data as []byte. Note: []byte needs to be handled by the user, deep copy required.
 // 返回解码后的数据[]byte, 注意：[]byte需要自行处理数据，深拷贝。否则会出现脏数据。
+// 接收数据帧时使用 BlockDecodeBuffer
 func (c *Codec) BlockDecodeBody(conn net.Conn, header, body []byte) ([]byte, int8, error) {
 	err := conn.SetReadDeadline(time.Now().Add(c.readTimeout))
 	if err != nil {
@@ -96,24 +167,93 @@ func (c *Codec) BlockDecodeBody(conn net.Conn, header, body []byte) ([]byte, int
 	return c.decodeBody(body)
 }
 
+// BlockDecodeBuffer 与 BlockDecodeBody 相同, 但消息体读入 packet.BufPool 分配的引用计数缓冲区, 不需要深拷贝.
+// 返回的数据引用 buf(压缩时为解压后的新内存), 调用方持有 buf 的一个引用, 使用完毕后调用 buf.Release
+func (c *Codec) BlockDecodeBuffer(conn net.Conn, header []byte) ([]byte, *packet2.Buffer, int8, error) {
+	err := conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	_, err = io.ReadFull(conn, header)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > c.maxIncomingSize {
+		return nil, nil, 0, ExceedMaxIncomingPacket(size)
+	}
+
+	buf := packet2.GetBuffer(int(size))
+	if _, err = io.ReadFull(conn, buf.Bytes()); err != nil {
+		buf.Release()
+		return nil, nil, 0, ReadBodyFailed(err)
+	}
+
+	data, head, err := c.decodeBody(buf.Bytes())
+	if err != nil {
+		buf.Release()
+		return nil, nil, head, err
+	}
+	return data, buf, head, nil
+}
+
+// DecodeDatagram 解码一个完整的数据报, 数据报必须恰好包含一帧: size<int32> | compressor<uint8> | type<int8> | body<bytes>
+// 未压缩时返回的 []byte 引用入参 data 的内存, 同 BlockDecodeBody 一样需要调用方自行深拷贝
+func (c *Codec) DecodeDatagram(data []byte) ([]byte, int8, error) {
+	if len(data) < HeadLen {
+		return nil, 0, ErrDatagramTooShort
+	}
+
+	size := binary.BigEndian.Uint32(data)
+	if size > c.maxIncomingSize {
+		return nil, 0, ExceedMaxIncomingPacket(size)
+	}
+
+	if int(size) != len(data)-HeadLen {
+		return nil, 0, ErrDatagramTruncated
+	}
+
+	return c.decodeBody(data[HeadLen:])
+}
+
 func (c *Codec) decodeBody(data []byte) ([]byte, int8, error) {
 	if len(data) < 2 {
 		return nil, 0, errors.New("read_body_failed")
 	}
 
-	//解析Gzip flag
-	b := data[0]
-	gzipped := b == byte(1)
+	//解析压缩算法 ID 与加密标识
+	flag := data[0]
+	id := CompressorID(flag &^ flagEncrypted)
 
 	//解析消息头
 	head := int8(data[1])
 
-	data = data[2:]
+	if flag&flagEncrypted != 0 {
+		if c.cipher == nil {
+			return nil, head, ErrDecryptFailed
+		}
+		plaintext, err := c.cipher.Open(data[2:], data[:2])
+		if err != nil {
+			return nil, head, err
+		}
+		data = plaintext
+	} else {
+		if c.cipher != nil {
+			return nil, head, ErrPlaintextFrame
+		}
+		data = data[2:]
+	}
 
-	if !gzipped || len(data) == 0 {
+	if id == CompressNone || len(data) == 0 {
 		return data, head, nil
 	}
+	compressor := GetCompressor(id)
+	if compressor == nil {
+		return nil, head, UnknownCompressor(id)
+	}
 	var err error
-	data, err = DecodeGzip(data)
+	data, err = compressor.Decompress(data, int(c.maxIncomingSize))
 	return data, head, err
 }
@@ -0,0 +1,102 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/golang/snappy"
+)
+
+/*
+   @Author: orbit-w
+   @File: compress
+   @2026 10月 周日 19:30
+*/
+
+// CompressorID 压缩算法标识, 编码在消息头的压缩标识字节中, 0 表示未压缩, 最高位保留给加密标识.
+// 旧协议中该字节为 gzipped<bool>, 因此 gzip 的 ID 固定为 1
+type CompressorID uint8
+
+const (
+	CompressNone   CompressorID = 0
+	CompressGzip   CompressorID = 1
+	CompressSnappy CompressorID = 2
+)
+
+// Compressor 压缩算法, 通过 RegisterCompressor 注册后即可在 Codec 中使用
+type Compressor interface {
+	ID() CompressorID
+	Name() string
+	Compress(data []byte) ([]byte, error)
+	//Decompress 解压后的数据超过 limit 字节时返回 ErrDecompressTooLarge,
+	//实现需要在分配内存之前检查, 防止很小的恶意数据帧声明巨大的解压长度
+	Decompress(data []byte, limit int) ([]byte, error)
+}
+
+var compressors [256]Compressor
+
+func init() {
+	RegisterCompressor(gzipCompressor{})
+	RegisterCompressor(snappyCompressor{})
+}
+
+// RegisterCompressor 注册压缩算法, ID 为 CompressNone, 超过 127 或者重复注册时 panic
+func RegisterCompressor(c Compressor) {
+	id := c.ID()
+	if id == CompressNone || byte(id)&flagEncrypted != 0 {
+		panic(fmt.Sprintf("compressor id %d is reserved", id))
+	}
+	if compressors[id] != nil {
+		panic(fmt.Sprintf("compressor %d already registered", id))
+	}
+	compressors[id] = c
+}
+
+// GetCompressor 获取已注册的压缩算法, CompressNone 或未注册时返回 nil
+func GetCompressor(id CompressorID) Compressor {
+	return compressors[id]
+}
+
+type gzipCompressor struct{}
+
+func (gzipCompressor) ID() CompressorID {
+	return CompressGzip
+}
+
+func (gzipCompressor) Name() string {
+	return "gzip"
+}
+
+func (gzipCompressor) Compress(data []byte) ([]byte, error) {
+	return EncodeGzip(data)
+}
+
+func (gzipCompressor) Decompress(data []byte, limit int) ([]byte, error) {
+	return DecodeGzipLimit(data, limit)
+}
+
+// snappyCompressor 压缩率低于 gzip, 但压缩与解压速度快一个数量级, 适合高频的小消息
+type snappyCompressor struct{}
+
+func (snappyCompressor) ID() CompressorID {
+	return CompressSnappy
+}
+
+func (snappyCompressor) Name() string {
+	return "snappy"
+}
+
+func (snappyCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCompressor) Decompress(data []byte, limit int) ([]byte, error) {
+	//snappy.Decode 按数据头声明的长度一次性分配内存
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, ErrDecompressTooLarge
+	}
+	return snappy.Decode(nil, data)
+}
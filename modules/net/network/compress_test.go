@@ -0,0 +1,135 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+/*
+   @Author: orbit-w
+   @File: compress_test
+   @2026 10月 周日 19:50
+*/
+
+func TestCodec_Compressor(t *testing.T) {
+	big := bytes.Repeat([]byte("meteor compress "), 64)
+	small := []byte("tiny")
+	for _, id := range []CompressorID{CompressNone, CompressGzip, CompressSnappy} {
+		codec := NewCodecWithCompressor(MaxIncomingPacket, id, CompressThreshold, 0)
+		for _, data := range [][]byte{big, small} {
+			pack, err := codec.Encode(data, TypeMessageRaw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			//小于压缩阈值的消息不压缩
+			flag := CompressorID(pack.Data()[HeadLen])
+			if len(data) < CompressThreshold && flag != CompressNone {
+				t.Fatalf("compressor %d: small payload compressed", id)
+			}
+			if len(data) >= CompressThreshold && flag != id {
+				t.Fatalf("compressor %d: unexpected flag %d", id, flag)
+			}
+
+			//解码端与编码端的压缩配置无关
+			out, _, err := NewCodec(MaxIncomingPacket, false, 0).DecodeDatagram(pack.Data())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, data) {
+				t.Fatalf("compressor %d: round trip mismatch", id)
+			}
+		}
+	}
+
+	pack := NewCodec(MaxIncomingPacket, false, 0).EncodeBody([]byte("x"), TypeMessageRaw)
+	pack.Data()[HeadLen] = 200
+	if _, _, err := NewCodec(MaxIncomingPacket, false, 0).DecodeDatagram(pack.Data()); err == nil {
+		t.Fatal("expected unknown compressor error")
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	data := benchPayload()
+	for _, id := range []CompressorID{CompressGzip, CompressSnappy} {
+		c := GetCompressor(id)
+		b.Run(c.Name(), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Compress(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// 很小的数据帧声明巨大的解压长度时, 在分配内存之前被拒绝
+func TestCodec_DecompressLimit(t *testing.T) {
+	codec := NewCodec(MaxIncomingPacket, false, 0)
+
+	//snappy 数据头为 varint 编码的解压长度
+	bomb := binary.AppendUvarint(nil, 1<<32-1)
+	bomb = append(bomb, 0, 0, 0, 0)
+	pack := codec.EncodeBody(bomb, TypeMessageRaw)
+	pack.Data()[HeadLen] = byte(CompressSnappy)
+	if _, _, err := codec.DecodeDatagram(pack.Data()); !errors.Is(err, ErrDecompressTooLarge) {
+		t.Fatalf("snappy: unexpected error %v", err)
+	}
+
+	//gzip 在解压过程中限制输出长度
+	zipped, _ := EncodeGzip(make([]byte, MaxIncomingPacket+1))
+	pack = codec.EncodeBody(zipped, TypeMessageRaw)
+	pack.Data()[HeadLen] = byte(CompressGzip)
+	if _, _, err := codec.DecodeDatagram(pack.Data()); !errors.Is(err, ErrDecompressTooLarge) {
+		t.Fatalf("gzip: unexpected error %v", err)
+	}
+
+	//不超过限制的数据正常解压
+	for _, id := range []CompressorID{CompressGzip, CompressSnappy} {
+		data := make([]byte, MaxIncomingPacket)
+		out, err := GetCompressor(id).Decompress(mustCompress(t, id, data), MaxIncomingPacket)
+		if err != nil || !bytes.Equal(out, data) {
+			t.Fatalf("compressor %d: round trip at limit failed: %v", id, err)
+		}
+	}
+}
+
+func mustCompress(t *testing.T, id CompressorID, data []byte) []byte {
+	compressed, err := GetCompressor(id).Compress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compressed
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	data := benchPayload()
+	for _, id := range []CompressorID{CompressGzip, CompressSnappy} {
+		c := GetCompressor(id)
+		compressed, _ := c.Compress(data)
+		b.Run(c.Name(), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(compressed))/float64(len(data)), "ratio")
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Decompress(compressed, MaxIncomingPacket); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchPayload 模拟 4KB 的业务消息
+func benchPayload() []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < 4096; i++ {
+		buf.WriteString("{\"uid\":10086,\"pos\":[12.5,3.25],\"hp\":980,\"buff\":[1,2,3]}")
+		buf.WriteByte(byte(i))
+	}
+	return buf.Bytes()
+}
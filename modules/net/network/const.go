@@ -15,13 +15,24 @@ const (
 
 const (
 	MaxIncomingPacket = 262144
-	HeadLen           = 4 //包头字节数
+	HeadLen           = 4                                 //包头字节数
+	FrameHeadLen      = HeadLen + compressSize + headSize //帧头字节数: size<int32> | compressor<uint8> | type<int8>
 
 	ReadTimeout  = time.Second * 60
 	WriteTimeout = time.Second * 5
+
+	CompressThreshold = 200 //默认压缩阈值, 长度小于该值的消息不压缩
 )
 
 const (
 	TypeMessageRaw = iota
 	TypeMessageHeartbeat
+	TypeMessageClose        //服务端关闭通知, 对端收到后应主动断开连接
+	TypeMessageProbe        //服务端心跳探测, 对端收到后应回复 TypeMessageHeartbeat
+	TypeMessageKeyExchange  //密钥交换, 开启加密时连接建立后的第一帧, 消息体: suite<uint8> | publicKey<32 bytes>
+	TypeMessageHandshake    //握手请求, 开启握手时客户端在密钥交换之后发送的第一帧, 消息体见 HandshakeRequest
+	TypeMessageHandshakeAck //握手应答, 消息体: status<uint8> | reason<string>
+	TypeMessageResume       //会话恢复请求, 开启会话恢复时客户端在握手之后发送, 消息体见 ResumeRequest
+	TypeMessageResumeAck    //会话恢复应答, 消息体: status<uint8> | token<bytes32> | recvSeq<uint64>
+	TypeMessageSeqAck       //会话恢复模式下确认已收到的消息序号, 对端据此清理重放窗口, 消息体: recvSeq<uint64>
 )
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -13,9 +14,36 @@ import (
 */
 
 var (
-	ErrCanceled = errors.New("context canceled")
+	ErrCanceled          = errors.New("context canceled")
+	ErrServerDraining    = errors.New("server draining") //GracefulStop 取消连接 ctx 时的 cause
+	ErrDatagramTooShort  = errors.New("datagram too short")
+	ErrDatagramTruncated = errors.New("datagram truncated")
+
+	ErrDecompressTooLarge = errors.New("decompressed size exceeds max incoming packet") //解压后的数据超过 maxIncomingSize
+
+	ErrUnsupportedCipher = errors.New("unsupported cipher suite")
+	ErrKeyExchangeFailed = errors.New("key exchange failed")
+	ErrPeerKeyMismatch   = errors.New("peer public key mismatch")
+	ErrDecryptFailed     = errors.New("decrypt failed")
+	ErrPlaintextFrame    = errors.New("unexpected plaintext frame") //协商加密后收到未加密的帧
+
+	ErrHandshakeFailed   = errors.New("handshake failed") //握手帧格式错误或者不是握手帧
+	ErrHandshakeRejected = errors.New("handshake rejected")
+	ErrHandshakeTimeout  = errors.New("handshake timeout")
+
+	ErrResumeFailed         = errors.New("resume failed")                 //会话恢复帧格式错误或者不是会话恢复帧
+	ErrSessionExpired       = errors.New("session expired")               //会话不存在或者超过保留时长未恢复
+	ErrReplayWindowExceeded = errors.New("resume replay window exceeded") //对端缺失的消息已被移出重放窗口
+
+	ErrChaosReset     = errors.New("chaos: connection reset") //故障注入重置了连接
+	ErrChaosTruncated = errors.New("chaos: write truncated")  //故障注入截断了写入并重置了连接
 )
 
+// IsDraining 判断连接的 ctx 是否因为服务端 GracefulStop 被取消, Stop 取消时返回 false
+func IsDraining(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrServerDraining)
+}
+
 // IsClosedConnError 判断是否为关闭连接错误
 func IsClosedConnError(err error) bool {
 	/*
@@ -42,3 +70,16 @@ func ReceiveBufPutErr(err error) error {
 func EncodeGzipFailed(err error) error {
 	return errors.New(fmt.Sprintf("encode gzip failed: %s", err.Error()))
 }
+
+func EncodeCompressFailed(name string, err error) error {
+	return errors.New(fmt.Sprintf("encode %s failed: %s", name, err.Error()))
+}
+
+// HandshakeRejected 服务端拒绝握手, reason 为服务端返回的原因
+func HandshakeRejected(reason string) error {
+	return fmt.Errorf("%w: %s", ErrHandshakeRejected, reason)
+}
+
+func UnknownCompressor(id CompressorID) error {
+	return errors.New(fmt.Sprintf("unknown compressor: %d", id))
+}
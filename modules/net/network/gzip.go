@@ -47,6 +47,25 @@ func EncodeGzip(data []byte) ([]byte, error) {
 	return compressedData, nil
 }
 
+// DecodeGzipLimit 与 DecodeGzip 相同, 解压后的数据超过 limit 字节时返回 ErrDecompressTooLarge
+func DecodeGzipLimit(buf []byte, limit int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	//多读一个字节用于判断是否超过 limit
+	data, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, ErrDecompressTooLarge
+	}
+	return data, nil
+}
+
 func DecodeGzip(buf []byte) ([]byte, error) {
 	// Create a new gzip reader for the bytes buffer
 	reader, err := gzip.NewReader(bytes.NewReader(buf))
@@ -0,0 +1,152 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: handshake
+   @2026 10月 周日 22:40
+*/
+
+const (
+	handshakeAccepted uint8 = iota
+	handshakeRejected
+)
+
+// HandshakeRequest 客户端在连接建立后(开启加密时在密钥交换之后)发送的握手信息,
+// 消息体: version<uint32> | token<bytes32> | count<uint16> | key<string> | value<string> | ...
+type HandshakeRequest struct {
+	Version    uint32            //客户端的协议版本
+	Token      []byte            //鉴权令牌
+	Metadata   map[string]string //客户端元数据: 平台、客户端版本等
+	RemoteAddr string            //客户端地址, 由服务端填充, 不参与编码
+}
+
+// HandshakeOptions 服务端握手配置: 连接建立后先读取客户端的握手帧并交给 Validate 校验,
+// 校验失败或者超过 Timeout 未完成握手的连接在交给业务处理之前被关闭
+type HandshakeOptions struct {
+	Timeout time.Duration //等待握手帧以及 Validate 返回的总时长, 0 时取 transport.HandshakeTimeout
+	//Validate 返回 error 时拒绝连接, error 的内容作为拒绝原因返回给客户端; ctx 在 Timeout 到期时结束.
+	//为空时接受所有完成握手的连接
+	Validate func(ctx context.Context, req *HandshakeRequest) error
+}
+
+// Encode 编码握手帧的消息体, Metadata 按 key 排序
+func (r *HandshakeRequest) Encode() []byte {
+	keys := make([]string, 0, len(r.Metadata))
+	for k := range r.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := packet2.Writer(4 + 4 + len(r.Token) + 2)
+	w.WriteUint32(r.Version)
+	w.WriteBytes32(r.Token)
+	w.WriteUint16(uint16(len(keys)))
+	for _, k := range keys {
+		w.WriteString(k)
+		w.WriteString(r.Metadata[k])
+	}
+	return w.Data()
+}
+
+// DecodeHandshakeRequest 解析握手帧的消息体, 返回的数据不引用 body
+func DecodeHandshakeRequest(body []byte) (*HandshakeRequest, error) {
+	d := bodyDecoder{data: body, ok: true}
+	req := &HandshakeRequest{
+		Version: d.uint32(),
+		Token:   d.bytes(d.uint32()),
+	}
+	count := d.uint16()
+	if count > 0 {
+		req.Metadata = make(map[string]string, count)
+	}
+	for i := 0; i < int(count) && d.ok; i++ {
+		k := d.bytes(uint32(d.uint16()))
+		v := d.bytes(uint32(d.uint16()))
+		req.Metadata[string(k)] = string(v)
+	}
+	if !d.ok || len(d.data) != 0 {
+		return nil, ErrHandshakeFailed
+	}
+	return req, nil
+}
+
+// EncodeHandshakeAck 编码握手应答帧的消息体, reject 为空时接受握手, 否则拒绝并携带 reject 的内容
+func EncodeHandshakeAck(reject error) []byte {
+	if reject == nil {
+		return []byte{handshakeAccepted}
+	}
+	w := packet2.Writer(1 + 2 + len(reject.Error()))
+	w.WriteUint8(handshakeRejected)
+	w.WriteString(reject.Error())
+	return w.Data()
+}
+
+// DecodeHandshakeAck 解析握手应答帧的消息体, 服务端拒绝时返回包含原因的 ErrHandshakeRejected
+func DecodeHandshakeAck(body []byte) error {
+	if len(body) == 0 {
+		return ErrHandshakeFailed
+	}
+	switch body[0] {
+	case handshakeAccepted:
+		return nil
+	case handshakeRejected:
+		d := bodyDecoder{data: body[1:], ok: true}
+		reason := d.bytes(uint32(d.uint16()))
+		if !d.ok {
+			return ErrHandshakeFailed
+		}
+		return HandshakeRejected(string(reason))
+	default:
+		return ErrHandshakeFailed
+	}
+}
+
+// bodyDecoder 带边界检查的握手与会话恢复消息解析, 任意一次读取越界后 ok 为 false
+type bodyDecoder struct {
+	data []byte
+	ok   bool
+}
+
+func (d *bodyDecoder) uint16() uint16 {
+	b := d.bytes(2)
+	if len(b) < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b)
+}
+
+func (d *bodyDecoder) uint32() uint32 {
+	b := d.bytes(4)
+	if len(b) < 4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (d *bodyDecoder) uint64() uint64 {
+	b := d.bytes(8)
+	if len(b) < 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// bytes 读取 n 个字节, 返回的数据为拷贝
+func (d *bodyDecoder) bytes(n uint32) []byte {
+	if !d.ok || uint64(n) > uint64(len(d.data)) {
+		d.ok = false
+		return nil
+	}
+	b := append([]byte(nil), d.data[:n]...)
+	d.data = d.data[n:]
+	return b
+}
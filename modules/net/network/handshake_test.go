@@ -0,0 +1,57 @@
+package network
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: handshake_test
+   @2026 10月 周日 23:35
+*/
+
+func TestHandshakeRequest_Codec(t *testing.T) {
+	req := &HandshakeRequest{
+		Version:  3,
+		Token:    []byte("secret"),
+		Metadata: map[string]string{"platform": "ios", "client": "1.2.0"},
+	}
+	data := req.Encode()
+	out, err := DecodeHandshakeRequest(data)
+	assert.NoError(t, err)
+	assert.Equal(t, req, out)
+
+	//Metadata 按 key 排序, 编码结果稳定
+	assert.Equal(t, data, req.Encode())
+
+	out, err = DecodeHandshakeRequest((&HandshakeRequest{}).Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), out.Version)
+	assert.Empty(t, out.Token)
+	assert.Nil(t, out.Metadata)
+}
+
+func TestHandshakeRequest_Malformed(t *testing.T) {
+	data := (&HandshakeRequest{Version: 1, Token: []byte("secret"), Metadata: map[string]string{"k": "v"}}).Encode()
+	for i := 0; i < len(data); i++ {
+		_, err := DecodeHandshakeRequest(data[:i])
+		assert.ErrorIs(t, err, ErrHandshakeFailed, i)
+	}
+	_, err := DecodeHandshakeRequest(append(data, 0))
+	assert.ErrorIs(t, err, ErrHandshakeFailed)
+}
+
+func TestHandshakeAck(t *testing.T) {
+	assert.NoError(t, DecodeHandshakeAck(EncodeHandshakeAck(nil)))
+
+	err := DecodeHandshakeAck(EncodeHandshakeAck(errors.New("unsupported version")))
+	assert.ErrorIs(t, err, ErrHandshakeRejected)
+	assert.Contains(t, err.Error(), "unsupported version")
+
+	assert.ErrorIs(t, DecodeHandshakeAck(nil), ErrHandshakeFailed)
+	assert.ErrorIs(t, DecodeHandshakeAck([]byte{handshakeRejected, 0}), ErrHandshakeFailed)
+	assert.ErrorIs(t, DecodeHandshakeAck([]byte{9}), ErrHandshakeFailed)
+}
@@ -0,0 +1,231 @@
+package network
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: limiter
+   @2026 10月 周日 18:45
+*/
+
+// LimitReason 触发限流的原因
+type LimitReason int8
+
+const (
+	LimitMaxConns   LimitReason = iota + 1 //超过最大并发连接数
+	LimitConnsPerIP                        //超过单 IP 最大连接数
+	LimitAcceptRate                        //超过新连接接入速率
+	LimitPacketRate                        //单连接入站数据帧速率超限
+	LimitByteRate                          //单连接入站字节速率超限
+)
+
+func (r LimitReason) String() string {
+	switch r {
+	case LimitMaxConns:
+		return "max_conns"
+	case LimitConnsPerIP:
+		return "conns_per_ip"
+	case LimitAcceptRate:
+		return "accept_rate"
+	case LimitPacketRate:
+		return "packet_rate"
+	case LimitByteRate:
+		return "byte_rate"
+	default:
+		return "unknown"
+	}
+}
+
+// LimitOptions 连接级限流与防洪配置, 各项为 0 时表示不限制.
+// 接入阶段超限的连接被直接关闭; 已建立的连接入站速率超限时被关闭
+type LimitOptions struct {
+	MaxConns      int     //最大并发连接数
+	MaxConnsPerIP int     //单 IP 最大并发连接数
+	AcceptRate    float64 //每秒允许接入的新连接数
+	AcceptBurst   int     //新连接接入的突发上限, 默认等于 AcceptRate
+	PacketRate    float64 //单连接每秒允许接收的数据帧数
+	PacketBurst   int     //单连接数据帧的突发上限, 默认等于 PacketRate
+	ByteRate      float64 //单连接每秒允许接收的字节数
+	ByteBurst     int     //单连接字节的突发上限, 默认等于 ByteRate
+
+	//OnLimit 触发限流时回调, 可用于打点统计, 不能阻塞
+	OnLimit func(reason LimitReason, remoteAddr string)
+}
+
+func (op *LimitOptions) notify(reason LimitReason, remoteAddr string) {
+	if op != nil && op.OnLimit != nil {
+		op.OnLimit(reason, remoteAddr)
+	}
+}
+
+// TokenBucket 令牌桶, nil 表示不限制
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket rate <= 0 时返回 nil(不限制); burst <= 0 时取 rate
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(rate, 1)
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		last:   time.Now(),
+	}
+}
+
+// Allow 尝试消耗 n 个令牌. 令牌桶满时允许超过 burst 的单次消耗, 之后的请求需要等待令牌补齐
+func (b *TokenBucket) Allow(n int) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < float64(n) && b.tokens < b.burst {
+		return false
+	}
+	b.tokens -= float64(n)
+	return true
+}
+
+// ConnLimiter 接入阶段的连接限流: 最大并发连接数, 单 IP 最大连接数, 接入速率.
+// nil 表示不限制
+type ConnLimiter struct {
+	op     *LimitOptions
+	accept *TokenBucket
+	mu     sync.Mutex
+	conns  int
+	perIP  map[string]int
+}
+
+// NewConnLimiter 未配置任何接入限制时返回 nil
+func NewConnLimiter(op *LimitOptions) *ConnLimiter {
+	if op == nil || (op.MaxConns <= 0 && op.MaxConnsPerIP <= 0 && op.AcceptRate <= 0) {
+		return nil
+	}
+	return &ConnLimiter{
+		op:     op,
+		accept: NewTokenBucket(op.AcceptRate, op.AcceptBurst),
+		perIP:  make(map[string]int),
+	}
+}
+
+// Acquire 检查新连接是否允许接入, 允许时占用一个连接名额, 连接关闭后需要调用 Release 归还
+func (l *ConnLimiter) Acquire(conn net.Conn) bool {
+	if l == nil {
+		return true
+	}
+	return l.AcquireAddr(conn.RemoteAddr())
+}
+
+// AcquireAddr 与 Acquire 相同, 用于没有 net.Conn 的虚拟连接(如 UDP 对端), 归还时调用 ReleaseAddr
+func (l *ConnLimiter) AcquireAddr(addr net.Addr) bool {
+	if l == nil {
+		return true
+	}
+	ip := hostOf(addr)
+	if !l.accept.Allow(1) {
+		l.op.notify(LimitAcceptRate, ip)
+		return false
+	}
+
+	l.mu.Lock()
+	if l.op.MaxConns > 0 && l.conns >= l.op.MaxConns {
+		l.mu.Unlock()
+		l.op.notify(LimitMaxConns, ip)
+		return false
+	}
+	if l.op.MaxConnsPerIP > 0 && l.perIP[ip] >= l.op.MaxConnsPerIP {
+		l.mu.Unlock()
+		l.op.notify(LimitConnsPerIP, ip)
+		return false
+	}
+	l.conns++
+	l.perIP[ip]++
+	l.mu.Unlock()
+	return true
+}
+
+func (l *ConnLimiter) Release(conn net.Conn) {
+	if l == nil {
+		return
+	}
+	l.ReleaseAddr(conn.RemoteAddr())
+}
+
+func (l *ConnLimiter) ReleaseAddr(addr net.Addr) {
+	if l == nil {
+		return
+	}
+	ip := hostOf(addr)
+	l.mu.Lock()
+	l.conns--
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+	l.mu.Unlock()
+}
+
+// InboundLimiter 单连接的入站速率限制, nil 表示不限制
+type InboundLimiter struct {
+	op      *LimitOptions
+	packets *TokenBucket
+	bytes   *TokenBucket
+}
+
+// NewInboundLimiter 未配置入站限制时返回 nil
+func NewInboundLimiter(op *LimitOptions) *InboundLimiter {
+	if op == nil || (op.PacketRate <= 0 && op.ByteRate <= 0) {
+		return nil
+	}
+	return &InboundLimiter{
+		op:      op,
+		packets: NewTokenBucket(op.PacketRate, op.PacketBurst),
+		bytes:   NewTokenBucket(op.ByteRate, op.ByteBurst),
+	}
+}
+
+// Allow 统计一帧大小为 size 字节的入站数据, 超限时回调 OnLimit 并返回 false
+func (l *InboundLimiter) Allow(size int, remoteAddr string) bool {
+	if l == nil {
+		return true
+	}
+	if !l.packets.Allow(1) {
+		l.op.notify(LimitPacketRate, remoteAddr)
+		return false
+	}
+	if !l.bytes.Allow(size) {
+		l.op.notify(LimitByteRate, remoteAddr)
+		return false
+	}
+	return true
+}
+
+func hostOf(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
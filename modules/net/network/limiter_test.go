@@ -0,0 +1,87 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: limiter_test
+   @2026 10月 周日 19:05
+*/
+
+func TestTokenBucket_Allow(t *testing.T) {
+	b := NewTokenBucket(100, 10)
+	for i := 0; i < 10; i++ {
+		if !b.Allow(1) {
+			t.Fatalf("burst token %d rejected", i)
+		}
+	}
+	if b.Allow(1) {
+		t.Fatal("expected bucket exhausted")
+	}
+	time.Sleep(time.Millisecond * 50)
+	if !b.Allow(1) {
+		t.Fatal("expected tokens refilled")
+	}
+
+	//令牌桶满时允许超过 burst 的单次消耗
+	b = NewTokenBucket(10, 10)
+	if !b.Allow(100) || b.Allow(1) {
+		t.Fatal("unexpected oversize consumption")
+	}
+
+	var unlimited *TokenBucket
+	if !unlimited.Allow(1 << 20) {
+		t.Fatal("nil bucket should not limit")
+	}
+}
+
+func TestConnLimiter_Acquire(t *testing.T) {
+	var reasons []LimitReason
+	l := NewConnLimiter(&LimitOptions{
+		MaxConns:      3,
+		MaxConnsPerIP: 2,
+		OnLimit: func(reason LimitReason, _ string) {
+			reasons = append(reasons, reason)
+		},
+	})
+
+	a1, a2, a3 := fakeConn("10.0.0.1:1"), fakeConn("10.0.0.1:2"), fakeConn("10.0.0.1:3")
+	b1, b2 := fakeConn("10.0.0.2:1"), fakeConn("10.0.0.2:2")
+	if !l.Acquire(a1) || !l.Acquire(a2) {
+		t.Fatal("expected acquire")
+	}
+	if l.Acquire(a3) {
+		t.Fatal("expected per ip limit")
+	}
+	if !l.Acquire(b1) {
+		t.Fatal("expected acquire")
+	}
+	if l.Acquire(b2) {
+		t.Fatal("expected max conns limit")
+	}
+	l.Release(a1)
+	if !l.Acquire(b2) {
+		t.Fatal("expected acquire after release")
+	}
+	if len(reasons) != 2 || reasons[0] != LimitConnsPerIP || reasons[1] != LimitMaxConns {
+		t.Fatalf("unexpected limit reasons: %v", reasons)
+	}
+}
+
+type addrConn struct {
+	net.Conn
+	addr net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func fakeConn(addr string) net.Conn {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	return &addrConn{addr: tcpAddr}
+}
@@ -0,0 +1,335 @@
+package network
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: metrics
+   @2026 10月 周日 23:50
+*/
+
+// rttBuckets 心跳 RTT 直方图的桶上限(秒)
+var rttBuckets = [...]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5}
+
+// Metrics 一组传输层指标, 所有方法都是并发安全的且允许 nil 接收者.
+// 每个服务端持有一份, 通过 RegisterMetrics 注册后由 WriteMetrics 以 Prometheus 文本格式导出
+type Metrics struct {
+	conns       atomic.Int64  //当前连接数
+	connsTotal  atomic.Uint64 //累计建立的连接数
+	packetsIn   atomic.Uint64
+	packetsOut  atomic.Uint64
+	payloadIn   atomic.Uint64 //消息原始字节数
+	payloadOut  atomic.Uint64
+	wireIn      atomic.Uint64 //线路字节数, 包含帧头, 压缩与加密之后
+	wireOut     atomic.Uint64
+	rttCount    atomic.Uint64
+	rttSum      atomic.Int64 //纳秒
+	rttBuckets  [len(rttBuckets)]atomic.Uint64
+	queueDepth  atomic.Pointer[func() int]
+	closeMu     sync.Mutex
+	closeReason map[string]uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{closeReason: make(map[string]uint64)}
+}
+
+// OnConnOpen 连接建立
+func (m *Metrics) OnConnOpen() {
+	if m == nil {
+		return
+	}
+	m.conns.Add(1)
+	m.connsTotal.Add(1)
+}
+
+// OnConnClose 连接关闭, reason 为关闭原因的简短描述, 如 "eof"、"heartbeat_timeout"
+func (m *Metrics) OnConnClose(reason string) {
+	if m == nil {
+		return
+	}
+	m.conns.Add(-1)
+	m.closeMu.Lock()
+	m.closeReason[reason]++
+	m.closeMu.Unlock()
+}
+
+// IncrementInboundTraffic 收到一条消息, amount 为消息原始长度
+func (m *Metrics) IncrementInboundTraffic(amount uint64) {
+	if m == nil {
+		return
+	}
+	m.packetsIn.Add(1)
+	m.payloadIn.Add(amount)
+}
+
+// IncrementOutboundTraffic 发送一条消息, amount 为消息原始长度
+func (m *Metrics) IncrementOutboundTraffic(amount uint64) {
+	if m == nil {
+		return
+	}
+	m.packetsOut.Add(1)
+	m.payloadOut.Add(amount)
+}
+
+// IncrementRealInboundTraffic 从线路上读取 amount 字节
+func (m *Metrics) IncrementRealInboundTraffic(amount uint64) {
+	if m == nil {
+		return
+	}
+	m.wireIn.Add(amount)
+}
+
+// IncrementRealOutboundTraffic 向线路写入 amount 字节
+func (m *Metrics) IncrementRealOutboundTraffic(amount uint64) {
+	if m == nil {
+		return
+	}
+	m.wireOut.Add(amount)
+}
+
+// ObserveRTT 记录一次心跳往返时延
+func (m *Metrics) ObserveRTT(rtt time.Duration) {
+	if m == nil {
+		return
+	}
+	m.rttCount.Add(1)
+	m.rttSum.Add(int64(rtt))
+	seconds := rtt.Seconds()
+	for i, le := range rttBuckets {
+		if seconds <= le {
+			m.rttBuckets[i].Add(1)
+		}
+	}
+}
+
+// SetQueueDepth 设置导出时统计发送队列积压字节数的回调
+func (m *Metrics) SetQueueDepth(fn func() int) {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Store(&fn)
+}
+
+// Conns 当前连接数
+func (m *Metrics) Conns() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.conns.Load()
+}
+
+func (m *Metrics) queued() int {
+	if fn := m.queueDepth.Load(); fn != nil {
+		return (*fn)()
+	}
+	return 0
+}
+
+func (m *Metrics) closeReasons() map[string]uint64 {
+	m.closeMu.Lock()
+	defer m.closeMu.Unlock()
+	reasons := make(map[string]uint64, len(m.closeReason))
+	for reason, n := range m.closeReason {
+		reasons[reason] = n
+	}
+	return reasons
+}
+
+var (
+	metricsMu  sync.RWMutex
+	registered = make(map[string]*Metrics)
+)
+
+// RegisterMetrics 以 name 注册 Metrics, 导出时作为 server 标签; 同名的 Metrics 被替换
+func RegisterMetrics(name string, m *Metrics) {
+	metricsMu.Lock()
+	registered[name] = m
+	metricsMu.Unlock()
+}
+
+// UnregisterMetrics 注销 name 对应的 Metrics, 已被替换时不做任何事
+func UnregisterMetrics(name string, m *Metrics) {
+	metricsMu.Lock()
+	if registered[name] == m {
+		delete(registered, name)
+	}
+	metricsMu.Unlock()
+}
+
+// metricsSnapshot 一份 Metrics 的快照, 用于导出与汇总
+type metricsSnapshot struct {
+	name                  string
+	conns                 int64
+	connsTotal            uint64
+	packetsIn, packetsOut uint64
+	payloadIn, payloadOut uint64
+	wireIn, wireOut       uint64
+	queued                int
+	rttCount              uint64
+	rttSum                time.Duration
+	rttBuckets            [len(rttBuckets)]uint64
+	closeReasons          map[string]uint64
+}
+
+func (m *Metrics) snapshot(name string) *metricsSnapshot {
+	s := &metricsSnapshot{
+		name:         name,
+		conns:        m.conns.Load(),
+		connsTotal:   m.connsTotal.Load(),
+		packetsIn:    m.packetsIn.Load(),
+		packetsOut:   m.packetsOut.Load(),
+		payloadIn:    m.payloadIn.Load(),
+		payloadOut:   m.payloadOut.Load(),
+		wireIn:       m.wireIn.Load(),
+		wireOut:      m.wireOut.Load(),
+		queued:       m.queued(),
+		rttCount:     m.rttCount.Load(),
+		rttSum:       time.Duration(m.rttSum.Load()),
+		closeReasons: m.closeReasons(),
+	}
+	for i := range s.rttBuckets {
+		s.rttBuckets[i] = m.rttBuckets[i].Load()
+	}
+	return s
+}
+
+func (s *metricsSnapshot) merge(o *metricsSnapshot) {
+	s.conns += o.conns
+	s.connsTotal += o.connsTotal
+	s.packetsIn += o.packetsIn
+	s.packetsOut += o.packetsOut
+	s.payloadIn += o.payloadIn
+	s.payloadOut += o.payloadOut
+	s.wireIn += o.wireIn
+	s.wireOut += o.wireOut
+	s.queued += o.queued
+	s.rttCount += o.rttCount
+	s.rttSum += o.rttSum
+	for i := range s.rttBuckets {
+		s.rttBuckets[i] += o.rttBuckets[i]
+	}
+	for reason, n := range o.closeReasons {
+		s.closeReasons[reason] += n
+	}
+}
+
+// MetricsAggregate 汇总所有 Metrics 的 server 标签值
+const MetricsAggregate = "all"
+
+// WriteMetrics 以 Prometheus 文本格式写出所有已注册的 Metrics, 以及 server="all" 的汇总
+func WriteMetrics(w io.Writer) error {
+	metricsMu.RLock()
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	snapshots := make([]*metricsSnapshot, 0, len(names)+1)
+	for _, name := range names {
+		snapshots = append(snapshots, registered[name].snapshot(name))
+	}
+	metricsMu.RUnlock()
+
+	all := &metricsSnapshot{name: MetricsAggregate, closeReasons: make(map[string]uint64)}
+	for _, s := range snapshots {
+		all.merge(s)
+	}
+	snapshots = append(snapshots, all)
+
+	bw := bufio.NewWriter(w)
+	family := func(name, typ, help string, value func(s *metricsSnapshot) string) {
+		_, _ = fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, s := range snapshots {
+			_, _ = fmt.Fprintf(bw, "%s{server=%q} %s\n", name, s.name, value(s))
+		}
+	}
+	formatUint := func(v uint64) string { return strconv.FormatUint(v, 10) }
+
+	family("meteor_transport_connections", "gauge", "Current number of connections.",
+		func(s *metricsSnapshot) string { return strconv.FormatInt(s.conns, 10) })
+	family("meteor_transport_connections_total", "counter", "Total number of accepted connections.",
+		func(s *metricsSnapshot) string { return formatUint(s.connsTotal) })
+	family("meteor_transport_packets_received_total", "counter", "Total number of messages received.",
+		func(s *metricsSnapshot) string { return formatUint(s.packetsIn) })
+	family("meteor_transport_packets_sent_total", "counter", "Total number of messages sent.",
+		func(s *metricsSnapshot) string { return formatUint(s.packetsOut) })
+	family("meteor_transport_payload_bytes_received_total", "counter", "Total message bytes received before decompression.",
+		func(s *metricsSnapshot) string { return formatUint(s.payloadIn) })
+	family("meteor_transport_payload_bytes_sent_total", "counter", "Total message bytes sent before compression.",
+		func(s *metricsSnapshot) string { return formatUint(s.payloadOut) })
+	family("meteor_transport_wire_bytes_received_total", "counter", "Total bytes read from the wire.",
+		func(s *metricsSnapshot) string { return formatUint(s.wireIn) })
+	family("meteor_transport_wire_bytes_sent_total", "counter", "Total bytes written to the wire.",
+		func(s *metricsSnapshot) string { return formatUint(s.wireOut) })
+	family("meteor_transport_compression_ratio", "gauge", "Wire bytes sent divided by message bytes sent.",
+		func(s *metricsSnapshot) string { return formatRatio(s.wireOut, s.payloadOut) })
+	family("meteor_transport_send_queue_bytes", "gauge", "Bytes queued for sending.",
+		func(s *metricsSnapshot) string { return strconv.Itoa(s.queued) })
+
+	const rtt = "meteor_transport_heartbeat_rtt_seconds"
+	_, _ = fmt.Fprintf(bw, "# HELP %s Heartbeat round trip time.\n# TYPE %s histogram\n", rtt, rtt)
+	for _, s := range snapshots {
+		for i, le := range rttBuckets {
+			_, _ = fmt.Fprintf(bw, "%s_bucket{server=%q,le=%q} %d\n", rtt, s.name, strconv.FormatFloat(le, 'g', -1, 64), s.rttBuckets[i])
+		}
+		_, _ = fmt.Fprintf(bw, "%s_bucket{server=%q,le=\"+Inf\"} %d\n", rtt, s.name, s.rttCount)
+		_, _ = fmt.Fprintf(bw, "%s_sum{server=%q} %s\n", rtt, s.name, strconv.FormatFloat(s.rttSum.Seconds(), 'g', -1, 64))
+		_, _ = fmt.Fprintf(bw, "%s_count{server=%q} %d\n", rtt, s.name, s.rttCount)
+	}
+
+	const closed = "meteor_transport_closed_total"
+	_, _ = fmt.Fprintf(bw, "# HELP %s Total number of closed connections by reason.\n# TYPE %s counter\n", closed, closed)
+	for _, s := range snapshots {
+		reasons := make([]string, 0, len(s.closeReasons))
+		for reason := range s.closeReasons {
+			reasons = append(reasons, reason)
+		}
+		sort.Strings(reasons)
+		for _, reason := range reasons {
+			_, _ = fmt.Fprintf(bw, "%s{server=%q,reason=%q} %d\n", closed, s.name, reason, s.closeReasons[reason])
+		}
+	}
+	return bw.Flush()
+}
+
+func formatRatio(wire, payload uint64) string {
+	if payload == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(wire)/float64(payload), 'g', 6, 64)
+}
+
+// MetricsHandler 返回以 Prometheus 文本格式导出指标的 http.Handler
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = WriteMetrics(w)
+	})
+}
+
+// ServeMetrics 在 addr 上启动 HTTP 服务, 以 /metrics 导出指标, 建议只监听内网或本地地址
+func ServeMetrics(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: time.Second * 5}
+	go func() {
+		_ = server.Serve(ln)
+	}()
+	return server, nil
+}
@@ -0,0 +1,61 @@
+package network
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: metrics_test
+   @2026 10月 周一 00:10
+*/
+
+func TestWriteMetrics(t *testing.T) {
+	a, b := NewMetrics(), NewMetrics()
+	RegisterMetrics("tcp://a", a)
+	RegisterMetrics("ws://b", b)
+	defer UnregisterMetrics("tcp://a", a)
+	defer UnregisterMetrics("ws://b", b)
+
+	a.OnConnOpen()
+	a.OnConnOpen()
+	a.OnConnClose("heartbeat_timeout")
+	a.IncrementOutboundTraffic(1000)
+	a.IncrementRealOutboundTraffic(250)
+	a.ObserveRTT(time.Millisecond * 20)
+	a.SetQueueDepth(func() int { return 64 })
+	b.OnConnOpen()
+	b.IncrementInboundTraffic(10)
+	b.ObserveRTT(time.Second * 3)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteMetrics(&buf))
+	out := buf.String()
+	for _, line := range []string{
+		`meteor_transport_connections{server="tcp://a"} 1`,
+		`meteor_transport_connections{server="all"} 2`,
+		`meteor_transport_connections_total{server="all"} 3`,
+		`meteor_transport_packets_received_total{server="ws://b"} 1`,
+		`meteor_transport_compression_ratio{server="tcp://a"} 0.25`,
+		`meteor_transport_send_queue_bytes{server="all"} 64`,
+		`meteor_transport_heartbeat_rtt_seconds_bucket{server="tcp://a",le="0.01"} 0`,
+		`meteor_transport_heartbeat_rtt_seconds_bucket{server="tcp://a",le="0.025"} 1`,
+		`meteor_transport_heartbeat_rtt_seconds_bucket{server="all",le="2.5"} 1`,
+		`meteor_transport_heartbeat_rtt_seconds_bucket{server="all",le="+Inf"} 2`,
+		`meteor_transport_heartbeat_rtt_seconds_count{server="all"} 2`,
+		`meteor_transport_closed_total{server="tcp://a",reason="heartbeat_timeout"} 1`,
+	} {
+		assert.True(t, strings.Contains(out, line+"\n"), line)
+	}
+
+	//nil Metrics 不统计
+	var m *Metrics
+	m.OnConnOpen()
+	m.ObserveRTT(time.Second)
+	assert.Equal(t, int64(0), m.Conns())
+}
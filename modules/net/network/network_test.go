@@ -1,6 +1,7 @@
 package network
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net"
@@ -27,3 +28,67 @@ func TestServer_handleConn(t *testing.T) {
 	time.Sleep(time.Second * 2)
 	fmt.Println("TestServer_handleConn")
 }
+
+func TestCodec_DecodeDatagram(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		codec := NewCodec(MaxIncomingPacket, gzipped, 0)
+		pack, err := codec.Encode([]byte("hello, datagram"), TypeMessageRaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, head, err := codec.DecodeDatagram(pack.Data())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head != TypeMessageRaw || string(data) != "hello, datagram" {
+			t.Fatalf("unexpected datagram: %d %s", head, string(data))
+		}
+
+		if _, _, err = codec.DecodeDatagram(pack.Data()[:pack.Len()-1]); err != ErrDatagramTruncated {
+			t.Fatalf("expected ErrDatagramTruncated, got %v", err)
+		}
+	}
+}
+
+func TestCodec_EncodeVector(t *testing.T) {
+	big := bytes.Repeat([]byte("meteor writev "), 64)
+	for _, gzipped := range []bool{false, true} {
+		codec := NewCodec(MaxIncomingPacket, gzipped, 0)
+		for _, data := range [][]byte{big, []byte("tiny")} {
+			pack, err := codec.Encode(data, TypeMessageRaw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			header := make([]byte, FrameHeadLen)
+			body, err := codec.EncodeVector(header, data, TypeMessageRaw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(append(header, body...), pack.Data()) {
+				t.Fatalf("gzip %v: EncodeVector differs from Encode", gzipped)
+			}
+		}
+	}
+
+	//加密时与 Encode 使用同一个发送序号
+	key := bytes.Repeat([]byte{1}, keySize)
+	sender := NewCodec(MaxIncomingPacket, false, 0)
+	receiver := NewCodec(MaxIncomingPacket, false, 0)
+	sealer, _ := NewCipher(CipherAES256GCM, key, key)
+	opener, _ := NewCipher(CipherAES256GCM, key, key)
+	sender.SetCipher(sealer)
+	receiver.SetCipher(opener)
+	for _, msg := range []string{"first", "second"} {
+		header := make([]byte, FrameHeadLen)
+		body, err := sender.EncodeVector(header, []byte(msg), TypeMessageRaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, _, err := receiver.DecodeDatagram(append(header, body...))
+		if err != nil || string(out) != msg {
+			t.Fatalf("unexpected sealed frame: %s %v", out, err)
+		}
+	}
+}
@@ -17,6 +17,8 @@ const (
 	TCP Protocol = "tcp"
 	KCP Protocol = "kcp"
 	UDP Protocol = "udp"
+	WS  Protocol = "ws"  //WebSocket, 每个二进制消息承载一帧 Codec 数据
+	MEM Protocol = "mem" //进程内的内存连接, 用于测试
 )
 
 type ConnHandle func(ctx context.Context, generic net.Conn, head, body []byte,
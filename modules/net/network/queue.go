@@ -0,0 +1,48 @@
+package network
+
+/*
+   @Author: orbit-w
+   @File: queue
+   @2026 10月 周日 10:20
+*/
+
+// QueuePolicy 发送队列积压超过高水位时的处理策略
+type QueuePolicy int8
+
+const (
+	QueuePolicyReject     QueuePolicy = iota //Send 返回 ErrSendQueueFull
+	QueuePolicyBlock                         //Send 阻塞直到积压降到低水位以下, SendContext 可通过 ctx 取消
+	QueuePolicyDropOldest                    //丢弃队列中最早的消息, 已交给发送协程的数据不会被丢弃
+	QueuePolicyDisconnect                    //断开慢消费者, Recv 返回 ErrSlowConsumer
+)
+
+func (p QueuePolicy) String() string {
+	switch p {
+	case QueuePolicyReject:
+		return "reject"
+	case QueuePolicyBlock:
+		return "block"
+	case QueuePolicyDropOldest:
+		return "drop_oldest"
+	case QueuePolicyDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// SendQueueOptions 单连接发送队列的背压配置, HighWatermark 为 0 时不限制.
+// 积压字节数包括 ControlBuffer 中缓存的消息以及已交给发送协程但未写入连接的数据
+type SendQueueOptions struct {
+	HighWatermark int         //积压字节数高水位, 超过后按 Policy 处理
+	LowWatermark  int         //QueuePolicyBlock 下积压降到该值以下时唤醒阻塞的 Send, 默认 HighWatermark / 2
+	Policy        QueuePolicy //默认 QueuePolicyReject
+}
+
+// Low 返回生效的低水位
+func (op *SendQueueOptions) Low() int {
+	if op.LowWatermark <= 0 || op.LowWatermark > op.HighWatermark {
+		return op.HighWatermark / 2
+	}
+	return op.LowWatermark
+}
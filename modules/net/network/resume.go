@@ -0,0 +1,112 @@
+package network
+
+import (
+	"encoding/binary"
+	"time"
+
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: resume
+   @2026 10月 周日 23:50
+*/
+
+const (
+	resumeAccepted uint8 = iota
+	resumeExpired
+	resumeWindowExceeded
+)
+
+// ResumeOptions 服务端会话恢复配置: 连接断开后会话保留 GracePeriod, 期间客户端携带会话令牌重连时
+// 在新连接上继续原来的会话, 双方重放对端未收到的消息, 业务处理函数不感知连接的替换
+type ResumeOptions struct {
+	GracePeriod time.Duration //连接断开后会话保留的时长, 0 时取 transport.ResumeGracePeriod
+	WindowSize  int           //重放窗口保留的已发送未确认消息的最大字节数, 0 时取 transport.ResumeWindowSize
+}
+
+// ResumeRequest 客户端在握手之后发送的会话恢复请求, Token 为空时请求建立新会话,
+// 消息体: token<bytes32> | recvSeq<uint64>
+type ResumeRequest struct {
+	Token   []byte //服务端分配的会话令牌
+	RecvSeq uint64 //客户端已收到的最后一条消息的序号
+}
+
+// ResumeAck 服务端的会话恢复应答
+type ResumeAck struct {
+	Token   []byte //会话令牌, 建立新会话时由服务端分配
+	RecvSeq uint64 //服务端已收到的最后一条消息的序号
+}
+
+func (r *ResumeRequest) Encode() []byte {
+	w := packet2.Writer(4 + len(r.Token) + 8)
+	w.WriteBytes32(r.Token)
+	w.WriteUint64(r.RecvSeq)
+	return w.Data()
+}
+
+// DecodeResumeRequest 解析会话恢复请求的消息体, 返回的数据不引用 body
+func DecodeResumeRequest(body []byte) (*ResumeRequest, error) {
+	d := bodyDecoder{data: body, ok: true}
+	req := &ResumeRequest{
+		Token:   d.bytes(d.uint32()),
+		RecvSeq: d.uint64(),
+	}
+	if !d.ok || len(d.data) != 0 {
+		return nil, ErrResumeFailed
+	}
+	return req, nil
+}
+
+// EncodeResumeAck 编码会话恢复应答的消息体, reject 为 ErrSessionExpired 或者 ErrReplayWindowExceeded 时拒绝恢复
+func EncodeResumeAck(ack *ResumeAck, reject error) []byte {
+	status := resumeAccepted
+	switch reject {
+	case nil:
+	case ErrReplayWindowExceeded:
+		status = resumeWindowExceeded
+	default:
+		status = resumeExpired
+	}
+	w := packet2.Writer(1 + 4 + len(ack.Token) + 8)
+	w.WriteUint8(status)
+	w.WriteBytes32(ack.Token)
+	w.WriteUint64(ack.RecvSeq)
+	return w.Data()
+}
+
+// DecodeResumeAck 解析会话恢复应答的消息体, 服务端拒绝时返回 ErrSessionExpired 或者 ErrReplayWindowExceeded
+func DecodeResumeAck(body []byte) (*ResumeAck, error) {
+	d := bodyDecoder{data: body, ok: true}
+	status := d.bytes(1)
+	ack := &ResumeAck{
+		Token:   d.bytes(d.uint32()),
+		RecvSeq: d.uint64(),
+	}
+	if !d.ok || len(d.data) != 0 {
+		return nil, ErrResumeFailed
+	}
+	switch status[0] {
+	case resumeAccepted:
+		return ack, nil
+	case resumeExpired:
+		return nil, ErrSessionExpired
+	case resumeWindowExceeded:
+		return nil, ErrReplayWindowExceeded
+	default:
+		return nil, ErrResumeFailed
+	}
+}
+
+// EncodeSeqAck 编码消息序号确认帧的消息体
+func EncodeSeqAck(recvSeq uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, recvSeq)
+}
+
+func DecodeSeqAck(body []byte) (uint64, error) {
+	if len(body) != 8 {
+		return 0, ErrResumeFailed
+	}
+	return binary.BigEndian.Uint64(body), nil
+}
@@ -0,0 +1,60 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: resume_test
+   @2026 10月 周一 01:10
+*/
+
+func TestResumeRequest_Codec(t *testing.T) {
+	req := &ResumeRequest{Token: []byte("0123456789abcdef"), RecvSeq: 42}
+	out, err := DecodeResumeRequest(req.Encode())
+	assert.NoError(t, err)
+	assert.Equal(t, req, out)
+
+	out, err = DecodeResumeRequest((&ResumeRequest{}).Encode())
+	assert.NoError(t, err)
+	assert.Empty(t, out.Token)
+
+	data := req.Encode()
+	for i := 0; i < len(data); i++ {
+		_, err = DecodeResumeRequest(data[:i])
+		assert.ErrorIs(t, err, ErrResumeFailed, i)
+	}
+	_, err = DecodeResumeRequest(append(data, 0))
+	assert.ErrorIs(t, err, ErrResumeFailed)
+}
+
+func TestResumeAck(t *testing.T) {
+	ack := &ResumeAck{Token: []byte("0123456789abcdef"), RecvSeq: 7}
+	out, err := DecodeResumeAck(EncodeResumeAck(ack, nil))
+	assert.NoError(t, err)
+	assert.Equal(t, ack, out)
+
+	_, err = DecodeResumeAck(EncodeResumeAck(&ResumeAck{}, ErrSessionExpired))
+	assert.ErrorIs(t, err, ErrSessionExpired)
+	_, err = DecodeResumeAck(EncodeResumeAck(&ResumeAck{}, ErrReplayWindowExceeded))
+	assert.ErrorIs(t, err, ErrReplayWindowExceeded)
+
+	_, err = DecodeResumeAck(nil)
+	assert.ErrorIs(t, err, ErrResumeFailed)
+	data := EncodeResumeAck(ack, nil)
+	data[0] = 9
+	_, err = DecodeResumeAck(data)
+	assert.ErrorIs(t, err, ErrResumeFailed)
+}
+
+func TestSeqAck(t *testing.T) {
+	seq, err := DecodeSeqAck(EncodeSeqAck(1 << 40))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1<<40), seq)
+
+	_, err = DecodeSeqAck([]byte{1, 2, 3})
+	assert.ErrorIs(t, err, ErrResumeFailed)
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"context"
+	"crypto/tls"
 	"github.com/orbit-w/meteor/bases/misc/utils"
 	"net"
 	"sync"
@@ -23,11 +24,14 @@ type Server struct {
 	listener net.Listener
 	rw       sync.RWMutex
 	ctx      context.Context
-	cancel   context.CancelFunc
+	cancel   context.CancelCauseFunc
 	handle   ConnHandle
 	bodyPool *sync.Pool
 	headPool *sync.Pool
 	op       *AcceptorOptions
+	limiter  *ConnLimiter
+	conns    map[net.Conn]struct{} //存活连接, GracefulStop 超时时强制关闭
+	wg       sync.WaitGroup        //存活连接的处理协程
 
 	readTimeout  time.Duration
 	writeTimeout time.Duration
@@ -36,14 +40,26 @@ type Server struct {
 type AcceptorOptions struct {
 	MaxIncomingPacket uint32
 	IsGzip            bool
+	Compressor        CompressorID //压缩算法, 为 CompressNone 且 IsGzip 为 true 时使用 gzip
+	CompressThreshold int          //压缩阈值, 0 时取 CompressThreshold, 小于 0 时压缩所有消息
 	NeedToMonitor     bool
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
+	TLSConfig         *tls.Config        //不为空时由具体的传输层使用 TLS 包装 listener
+	HeartbeatInterval time.Duration      //对端空闲超过该时长时服务端发送心跳探测
+	HeartbeatTimeout  time.Duration      //对端超过该时长未发送任何数据帧时关闭连接, 0 表示不检测
+	Limit             LimitOptions       //连接级限流与防洪
+	Encryption        *EncryptionOptions //不为空时要求客户端在连接建立后先完成密钥交换
+	Metrics           *Metrics           //不为空时连接的流量、RTT 与关闭原因计入该指标
+	SendQueue         SendQueueOptions   //单连接发送队列的高低水位与背压策略
+	Chaos             *ChaosOptions      //不为空时为每条连接注入故障, 用于测试
+	Handshake         *HandshakeOptions  //不为空时要求客户端在交给业务处理之前完成握手与鉴权
+	Resume            *ResumeOptions     //不为空时开启会话恢复, 断线的会话保留一段时间等待客户端恢复
 }
 
 func (ins *Server) Serve(p Protocol, listener net.Listener, _handle ConnHandle, ops ...*AcceptorOptions) {
 	op := parseAndWrapOP(ops...)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 	ins.rw = sync.RWMutex{}
 	ins.readTimeout = op.ReadTimeout
 	ins.writeTimeout = op.WriteTimeout
@@ -54,6 +70,7 @@ func (ins *Server) Serve(p Protocol, listener net.Listener, _handle ConnHandle,
 	ins.handle = _handle
 	ins.listener = listener
 	ins.op = op
+	ins.limiter = NewConnLimiter(&op.Limit)
 
 	ins.protocol = p
 
@@ -67,18 +84,55 @@ func (ins *Server) Addr() string {
 	return ins.listener.Addr().String()
 }
 
+// CCU 当前存活的连接数
+func (ins *Server) CCU() int32 {
+	return atomic.LoadInt32(&ins.ccu)
+}
+
 // Stop stops the server
 // 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
 func (ins *Server) Stop() error {
+	ins.stop(nil)
+	return nil
+}
+
+// GracefulStop 停止接收新连接并以 ErrServerDraining 取消 ctx 通知所有存活连接关闭, 阻塞直到所有连接的处理协程退出;
+// 参数 ctx 结束时强制关闭剩余连接并返回 ctx.Err()
+func (ins *Server) GracefulStop(ctx context.Context) error {
+	ins.stop(ErrServerDraining)
+	//确保 Stop 之后不会再有新连接加入 wg
+	ins.rw.Lock()
+	ins.rw.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ins.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		ins.rw.RLock()
+		for conn := range ins.conns {
+			_ = conn.Close()
+		}
+		ins.rw.RUnlock()
+		return ctx.Err()
+	}
+}
+
+// stop 以 cause 取消连接的 ctx 并关闭 listener, cause 为 nil 时连接不会收到关闭通知
+func (ins *Server) stop(cause error) {
 	if ins.state.CompareAndSwap(TypeWorking, TypeStopped) {
 		if ins.cancel != nil {
-			ins.cancel()
+			ins.cancel(cause)
 		}
 		if ins.listener != nil {
 			_ = ins.listener.Close()
 		}
 	}
-	return nil
 }
 
 func (ins *Server) acceptLoop() {
@@ -99,18 +153,66 @@ func (ins *Server) acceptLoop() {
 }
 
 func (ins *Server) handleConn(conn net.Conn) {
+	if !ins.limiter.Acquire(conn) {
+		_ = conn.Close()
+		return
+	}
+
+	ins.rw.Lock()
+	if ins.state.Load() == TypeStopped {
+		ins.rw.Unlock()
+		ins.limiter.Release(conn)
+		_ = conn.Close()
+		return
+	}
+	if ins.conns == nil {
+		ins.conns = make(map[net.Conn]struct{})
+	}
+	ins.conns[conn] = struct{}{}
+	ins.wg.Add(1)
+	ins.rw.Unlock()
+	atomic.AddInt32(&ins.ccu, 1)
+
 	utils.GoRecoverPanic(func() {
 		head := ins.headPool.Get().(*Buffer)
 		body := ins.bodyPool.Get().(*Buffer)
 		defer func() {
 			ins.headPool.Put(head)
 			ins.bodyPool.Put(body)
+			ins.rw.Lock()
+			delete(ins.conns, conn)
+			ins.rw.Unlock()
+			atomic.AddInt32(&ins.ccu, -1)
+			ins.limiter.Release(conn)
+			ins.wg.Done()
 		}()
 
 		ins.handle(ins.ctx, conn, head.Bytes, body.Bytes, ins.op)
 	})
 }
 
+// NewCodec 根据配置创建编解码器
+func (op *AcceptorOptions) NewCodec() *Codec {
+	return NewCodecWithCompressor(op.MaxIncomingPacket, ResolveCompressor(op.Compressor, op.IsGzip),
+		ResolveCompressThreshold(op.CompressThreshold), op.ReadTimeout)
+}
+
+// ResolveCompressor 兼容 IsGzip 配置: 未指定压缩算法且 isGzip 为 true 时使用 gzip
+func ResolveCompressor(id CompressorID, isGzip bool) CompressorID {
+	if id == CompressNone && isGzip {
+		return CompressGzip
+	}
+	return id
+}
+
+// ResolveCompressThreshold 0 时取默认压缩阈值
+func ResolveCompressThreshold(threshold int) int {
+	if threshold == 0 {
+		return CompressThreshold
+	}
+	return threshold
+}
+
 func DefaultAcceptorOptions() *AcceptorOptions {
 	return &AcceptorOptions{
 		MaxIncomingPacket: MaxIncomingPacket,
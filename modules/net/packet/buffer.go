@@ -0,0 +1,76 @@
+package packet
+
+import (
+	"sync/atomic"
+)
+
+/*
+   @Author: orbit-w
+   @File: buffer
+   @2026 10月 周日 14:05
+*/
+
+// Buffer 引用计数的池化缓冲区, 用于接收路径: 一帧数据读入一个 Buffer, 帧内的多条消息共享该 Buffer,
+// 每条消息持有一个引用, 引用计数归零时归还到 BufPool. 归还之后不能再访问 Bytes 返回的数据
+type Buffer struct {
+	refs atomic.Int32
+	pool *BufPool
+	pack IPacket //为 nil 时 data 不来自缓冲池(超过缓冲池的最大尺寸)
+	data []byte
+}
+
+// GetBuffer 从默认缓冲池获取长度为 size 的 Buffer, 引用计数为 1
+func GetBuffer(size int) *Buffer {
+	return defPool.GetBuffer(size)
+}
+
+// GetBuffer 获取长度为 size 的 Buffer, 引用计数为 1, 内容未初始化
+func (p *BufPool) GetBuffer(size int) *Buffer {
+	b := &Buffer{pool: p}
+	b.refs.Store(1)
+	if pack := p.Get(size); pack != nil {
+		//缓冲池按容量向下取整分桶, 取出的缓冲区容量可能小于 size
+		if pack.Cap() >= size {
+			b.pack = pack
+			b.data = pack.Data()[:size]
+			return b
+		}
+		_ = p.Put(pack)
+	}
+	b.data = make([]byte, size)
+	return b
+}
+
+// Bytes 返回缓冲区的数据
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Retain 增加一个引用
+func (b *Buffer) Retain() {
+	b.refs.Add(1)
+}
+
+// Release 释放一个引用, 引用计数归零时归还到缓冲池. nil 时什么也不做
+func (b *Buffer) Release() {
+	if b == nil {
+		return
+	}
+	refs := b.refs.Add(-1)
+	switch {
+	case refs == 0:
+		if b.pack != nil {
+			b.pack.Reset()
+			_ = b.pool.Put(b.pack)
+		}
+		b.pack = nil
+		b.data = nil
+	case refs < 0:
+		panic("packet: Buffer released too many times")
+	}
+}
+
+// Refs 当前的引用计数
+func (b *Buffer) Refs() int32 {
+	return b.refs.Load()
+}
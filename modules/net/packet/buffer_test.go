@@ -0,0 +1,44 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: buffer_test
+   @2026 10月 周日 14:40
+*/
+
+func Test_BufferRefs(t *testing.T) {
+	p := NewPool(maxSize)
+	b := p.GetBuffer(100)
+	assert.Len(t, b.Bytes(), 100)
+	assert.Equal(t, int32(1), b.Refs())
+
+	b.Retain()
+	b.Release()
+	assert.Equal(t, int32(1), b.Refs())
+	assert.NotNil(t, b.Bytes())
+
+	b.Release()
+	assert.Equal(t, int32(0), b.Refs())
+	assert.Nil(t, b.Bytes())
+	assert.Panics(t, b.Release)
+
+	var nb *Buffer
+	assert.NotPanics(t, nb.Release)
+}
+
+func Test_BufferOversize(t *testing.T) {
+	p := NewPool(1024)
+	b := p.GetBuffer(4096)
+	assert.Len(t, b.Bytes(), 4096)
+	b.Release()
+
+	b = p.GetBuffer(0)
+	assert.Len(t, b.Bytes(), 0)
+	b.Release()
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gogo/protobuf/proto"
+	jsoniter "github.com/json-iterator/go"
+)
+
+/*
+   @Author: orbit-w
+   @File: codec
+   @2026 10月 周日 23:10
+*/
+
+// Codec 消息体的序列化方式
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	JSON  Codec = jsonCodec{}
+	Proto Codec = protoCodec{}
+)
+
+var (
+	jsonAPI = jsoniter.ConfigCompatibleWithStandardLibrary
+
+	errNotProtoMessage = errors.New("value does not implement proto.Message")
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return jsonAPI.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return jsonAPI.Unmarshal(data, v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string {
+	return "proto"
+}
+
+func (protoCodec) Marshal(v any) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, errNotProtoMessage
+	}
+	return proto.Marshal(msg)
+}
+
+func (protoCodec) Unmarshal(data []byte, v any) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return errNotProtoMessage
+	}
+	return proto.Unmarshal(data, msg)
+}
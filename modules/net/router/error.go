@@ -0,0 +1,25 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+   @Author: orbit-w
+   @File: error
+   @2026 10月 周日 23:10
+*/
+
+var (
+	ErrMessageTooShort = errors.New("message too short")
+	ErrUnknownMessage  = errors.New("unknown message id")
+)
+
+func DecodeFailed(id uint32, err error) error {
+	return fmt.Errorf("decode message %d failed: %w", id, err)
+}
+
+func HandlerPanic(id uint32, x any) error {
+	return errors.New(fmt.Sprintf("handle message %d panic: %v", id, x))
+}
@@ -0,0 +1,217 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/mlog"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/orbit-w/meteor/modules/net/transport"
+	"go.uber.org/zap"
+)
+
+/*
+   @Author: orbit-w
+   @File: router
+   @2026 10月 周日 23:10
+*/
+
+// 消息格式: msg id<uint32> | body<bytes>, body 由 Router 的 Codec 序列化
+
+// Context 一次消息处理的上下文, 只在 HandlerFunc 返回前有效, Body 引用接收缓冲区, 需要保留时自行拷贝
+type Context struct {
+	context.Context
+	Conn   transport.IConn
+	MsgID  uint32
+	Body   []byte
+	router *Router
+	values map[string]any
+}
+
+// Reply 以 Router 的 Codec 序列化 v 并以消息 id 发送给对端
+func (c *Context) Reply(id uint32, v any) error {
+	data, err := c.router.Encode(id, v)
+	if err != nil {
+		return err
+	}
+	return c.Conn.Send(data)
+}
+
+// Set 保存中间件之间传递的数据, 如鉴权后的玩家 ID
+func (c *Context) Set(key string, v any) {
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = v
+}
+
+func (c *Context) Get(key string) (any, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+type HandlerFunc func(c *Context) error
+
+// Middleware 包装 HandlerFunc, 按 Use 的顺序由外到内执行
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Router 按消息 ID 将消息分发到注册的 HandlerFunc, 需要在开始 Serve 前完成注册
+type Router struct {
+	codec       Codec
+	handlers    map[uint32]HandlerFunc
+	middlewares []Middleware
+	chain       HandlerFunc //middlewares 包装的 route, 包括未注册的消息 id
+	onUnknown   func(c *Context)
+	onError     func(c *Context, err error)
+	logger      *mlog.Logger
+}
+
+// New codec 为空时使用 Proto
+func New(codec Codec) *Router {
+	if codec == nil {
+		codec = Proto
+	}
+	r := &Router{
+		codec:    codec,
+		handlers: make(map[uint32]HandlerFunc),
+		logger:   mlog.With(zap.String("Module", "Router")),
+	}
+	r.onUnknown = func(c *Context) {
+		r.logger.Warn("Unknown message id", zap.Uint32("MsgID", c.MsgID), zap.Int("Size", len(c.Body)))
+	}
+	r.onError = func(c *Context, err error) {
+		r.logger.Error("Handle message failed", zap.Uint32("MsgID", c.MsgID), zap.Error(err))
+	}
+	r.chain = r.route
+	return r
+}
+
+// Use 注册中间件, 对所有消息生效(与 Handler 的注册顺序无关), 未注册的消息 id 同样经过中间件
+func (r *Router) Use(mw ...Middleware) {
+	r.middlewares = append(r.middlewares, mw...)
+	h := r.route
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	r.chain = h
+}
+
+// HandleRaw 注册消息 id 的处理函数, 消息体不解码; 重复注册时 panic
+func (r *Router) HandleRaw(id uint32, h HandlerFunc) {
+	if _, ok := r.handlers[id]; ok {
+		panic(fmt.Sprintf("router: message id %d already registered", id))
+	}
+	r.handlers[id] = h
+}
+
+// Handle 注册消息 id 的处理函数, 消息体以 Router 的 Codec 解码为 *T, 解码失败时不调用 h
+func Handle[T any](r *Router, id uint32, h func(c *Context, req *T) error) {
+	r.HandleRaw(id, func(c *Context) error {
+		req := new(T)
+		if err := r.codec.Unmarshal(c.Body, req); err != nil {
+			return DecodeFailed(c.MsgID, err)
+		}
+		return h(c, req)
+	})
+}
+
+// OnUnknown 设置未注册的消息 id 的回调, 默认打印警告日志
+func (r *Router) OnUnknown(fn func(c *Context)) {
+	r.onUnknown = fn
+}
+
+// OnError 设置处理函数返回错误时的回调, 默认打印错误日志
+func (r *Router) OnError(fn func(c *Context, err error)) {
+	r.onError = fn
+}
+
+// Encode 编码消息: msg id<uint32> | body<bytes>
+func (r *Router) Encode(id uint32, v any) ([]byte, error) {
+	body, err := r.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	w := packet2.Writer(4 + len(body))
+	w.WriteUint32(id)
+	w.Write(body)
+	return w.Data(), nil
+}
+
+// Dispatch 解析消息 id 并调用对应的处理函数, 未注册的 id 返回 ErrUnknownMessage
+func (r *Router) Dispatch(ctx context.Context, conn transport.IConn, in []byte) error {
+	reader := packet2.Reader(in)
+	id, err := reader.ReadUint32()
+	if err != nil {
+		return ErrMessageTooShort
+	}
+
+	c := &Context{
+		Context: ctx,
+		Conn:    conn,
+		MsgID:   id,
+		Body:    reader.Remain(),
+		router:  r,
+	}
+	if err = r.chain(c); err != nil && !errors.Is(err, ErrUnknownMessage) {
+		r.onError(c, err)
+	}
+	return err
+}
+
+// route 调用消息 id 对应的处理函数, 位于中间件链的最内层
+func (r *Router) route(c *Context) error {
+	h, ok := r.handlers[c.MsgID]
+	if !ok {
+		r.onUnknown(c)
+		return ErrUnknownMessage
+	}
+	return h(c)
+}
+
+// Serve 在当前协程中循环接收 conn 的消息并分发, 直到 conn 关闭; 可以直接作为 transport.Serve 的 _handle
+func (r *Router) Serve(conn transport.IConn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for {
+		msg, err := transport.RecvMessage(ctx, conn)
+		if err != nil {
+			return
+		}
+		_ = r.Dispatch(ctx, conn, msg.Data)
+		msg.Release()
+	}
+}
+
+// Recovery 捕获处理函数的 panic 并转换为错误
+func Recovery() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) (err error) {
+			defer func() {
+				if x := recover(); x != nil {
+					debug.PrintStack()
+					err = HandlerPanic(c.MsgID, x)
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// Logging 打印每条消息的处理耗时, logger 为空时使用全局 logger
+func Logging(logger *mlog.Logger) Middleware {
+	if logger == nil {
+		logger = mlog.With(zap.String("Module", "Router"))
+	}
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			start := time.Now()
+			err := next(c)
+			logger.Debug("Handle message", zap.Uint32("MsgID", c.MsgID),
+				zap.Duration("Cost", time.Since(start)), zap.Error(err))
+			return err
+		}
+	}
+}
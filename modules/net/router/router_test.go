@@ -0,0 +1,197 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/orbit-w/meteor/modules/net/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: router_test
+   @2026 10月 周日 23:40
+*/
+
+type loginReq struct {
+	Account string `json:"account"`
+}
+
+type loginResp struct {
+	PlayerID int64 `json:"player_id"`
+}
+
+func Test_RouterJSON(t *testing.T) {
+	r := New(JSON)
+	Handle(r, 1001, func(c *Context, req *loginReq) error {
+		assert.Equal(t, "orbit", req.Account)
+		return c.Reply(1002, &loginResp{PlayerID: 1})
+	})
+
+	conn := &mockConn{}
+	in, err := r.Encode(1001, &loginReq{Account: "orbit"})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Dispatch(context.Background(), conn, in))
+
+	assert.Len(t, conn.sent, 1)
+	resp := new(loginResp)
+	id := decode(t, JSON, conn.sent[0], resp)
+	assert.Equal(t, uint32(1002), id)
+	assert.Equal(t, int64(1), resp.PlayerID)
+
+	//解码失败
+	err = r.Dispatch(context.Background(), conn, []byte{0, 0, 0x03, 0xE9, '{'})
+	assert.Error(t, err)
+}
+
+func Test_RouterProto(t *testing.T) {
+	r := New(Proto)
+	Handle(r, 1, func(c *Context, req *types.StringValue) error {
+		return c.Reply(2, &types.StringValue{Value: "echo " + req.Value})
+	})
+
+	conn := &mockConn{}
+	in, err := r.Encode(1, &types.StringValue{Value: "hello"})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Dispatch(context.Background(), conn, in))
+
+	resp := new(types.StringValue)
+	assert.Equal(t, uint32(2), decode(t, Proto, conn.sent[0], resp))
+	assert.Equal(t, "echo hello", resp.Value)
+}
+
+func Test_RouterMiddleware(t *testing.T) {
+	errUnauthorized := errors.New("unauthorized")
+	var order []string
+	r := New(JSON)
+	r.Use(Recovery(), func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			order = append(order, "auth")
+			if c.MsgID != 1 {
+				return errUnauthorized
+			}
+			c.Set("player", int64(7))
+			return next(c)
+		}
+	})
+	r.HandleRaw(1, func(c *Context) error {
+		order = append(order, "handler")
+		player, ok := c.Get("player")
+		assert.True(t, ok)
+		assert.Equal(t, int64(7), player)
+		return nil
+	})
+	r.HandleRaw(2, func(c *Context) error {
+		t.Fatal("unauthorized message reached handler")
+		return nil
+	})
+
+	var handleErr error
+	r.OnError(func(c *Context, err error) {
+		handleErr = err
+	})
+
+	assert.NoError(t, r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 1}))
+	assert.Equal(t, []string{"auth", "handler"}, order)
+
+	assert.Equal(t, errUnauthorized, r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 2}))
+	assert.Equal(t, errUnauthorized, handleErr)
+}
+
+// 中间件与注册顺序无关, 未注册的消息 id 同样经过中间件
+func Test_RouterMiddlewareAfterHandle(t *testing.T) {
+	var seen []uint32
+	r := New(JSON)
+	r.HandleRaw(1, func(c *Context) error {
+		return nil
+	})
+	r.OnUnknown(func(c *Context) {})
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			seen = append(seen, c.MsgID)
+			return next(c)
+		}
+	})
+
+	assert.NoError(t, r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 1}))
+	assert.Equal(t, ErrUnknownMessage, r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 9}))
+	assert.Equal(t, []uint32{1, 9}, seen)
+}
+
+func Test_RouterRecovery(t *testing.T) {
+	r := New(JSON)
+	r.Use(Recovery())
+	r.HandleRaw(1, func(c *Context) error {
+		panic("boom")
+	})
+	r.OnError(func(c *Context, err error) {})
+	err := r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func Test_RouterUnknown(t *testing.T) {
+	unknown := make(chan uint32, 1)
+	r := New(JSON)
+	r.OnUnknown(func(c *Context) {
+		unknown <- c.MsgID
+	})
+	assert.Equal(t, ErrUnknownMessage, r.Dispatch(context.Background(), nil, []byte{0, 0, 0, 9}))
+	assert.Equal(t, uint32(9), <-unknown)
+	assert.Equal(t, ErrMessageTooShort, r.Dispatch(context.Background(), nil, []byte{0, 1}))
+}
+
+func Test_RouterServe(t *testing.T) {
+	r := New(JSON)
+	r.Use(Recovery(), Logging(nil))
+	Handle(r, 1001, func(c *Context, req *loginReq) error {
+		return c.Reply(1002, &loginResp{PlayerID: 42})
+	})
+
+	server, err := transport.Serve("tcp", "127.0.0.1:0", r.Serve)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := transport.DialWithOps(context.Background(), server.Addr())
+	defer conn.Close()
+	in, err := r.Encode(1001, &loginReq{Account: "orbit"})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.Send(in))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	out, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	resp := new(loginResp)
+	assert.Equal(t, uint32(1002), decode(t, JSON, out, resp))
+	assert.Equal(t, int64(42), resp.PlayerID)
+}
+
+func decode(t *testing.T, codec Codec, data []byte, v any) uint32 {
+	assert.True(t, len(data) >= 4)
+	id := uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+	assert.NoError(t, codec.Unmarshal(data[4:], v))
+	return id
+}
+
+type mockConn struct {
+	sent [][]byte
+}
+
+func (m *mockConn) Send(data []byte) error {
+	m.sent = append(m.sent, data)
+	return nil
+}
+
+func (m *mockConn) Recv(ctx context.Context) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *mockConn) Close() error {
+	return nil
+}
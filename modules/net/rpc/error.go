@@ -0,0 +1,31 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+   @Author: orbit-w
+   @File: error
+   @2026 10月 周日 21:30
+*/
+
+// RemoteError 对端 Handler 返回的错误
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "rpc remote error: " + e.Message
+}
+
+// IsRemoteError 判断 err 是否为对端返回的错误, 而不是本端的超时或者连接错误
+func IsRemoteError(err error) bool {
+	var re *RemoteError
+	return errors.As(err, &re)
+}
+
+func MethodNotFound(method string) error {
+	return errors.New(fmt.Sprintf("method not found: %s", method))
+}
@@ -0,0 +1,249 @@
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+
+	"github.com/orbit-w/meteor/bases/misc/utils"
+	"github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/orbit-w/meteor/modules/net/transport"
+)
+
+/*
+   @Author: orbit-w
+   @File: rpc
+   @2026 10月 周日 21:30
+*/
+
+// 消息类型
+const (
+	kindRequest  int8 = iota + 1 //kind<int8> | seq<uint32> | method<uint16 length + bytes> | body<bytes>
+	kindResponse                 //kind<int8> | seq<uint32> | body<bytes>
+	kindError                    //kind<int8> | seq<uint32> | message<bytes>
+)
+
+const headSize = 5 //kind<int8> | seq<uint32>
+
+// Handler 处理对端的请求, 返回的 error 以 *RemoteError 的形式返回给调用方.
+// ctx 在连接关闭时取消; req 引用接收缓冲区, 只在 Handler 返回前有效, 需要保留时自行拷贝, 直接作为响应返回是安全的
+type Handler func(ctx context.Context, req []byte) ([]byte, error)
+
+// Registry 方法名到 Handler 的注册表, 可以在多条连接间共享, 需要在连接建立前完成注册
+type Registry struct {
+	handlers map[string]Handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]Handler)}
+}
+
+// Register 注册方法, 重复注册时 panic
+func (r *Registry) Register(method string, handler Handler) {
+	if _, ok := r.handlers[method]; ok {
+		panic("rpc: method already registered: " + method)
+	}
+	r.handlers[method] = handler
+}
+
+func (r *Registry) get(method string) Handler {
+	if r == nil {
+		return nil
+	}
+	return r.handlers[method]
+}
+
+type result struct {
+	resp []byte
+	err  error
+}
+
+// Conn 在 transport.IConn 之上提供请求/响应语义, 双方都可以发起 Call 并处理对端的请求.
+// Conn 独占 IConn 的 Recv, 连接关闭后所有未完成的 Call 返回连接关闭的原因
+type Conn struct {
+	conn     transport.IConn
+	registry *Registry
+	seq      atomic.Uint32
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[uint32]chan result
+	err     error //连接关闭的原因
+	done    chan struct{}
+}
+
+// NewConn 创建 Conn 并开始接收消息, registry 为空时不处理对端的请求
+func NewConn(conn transport.IConn, registry *Registry) *Conn {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		conn:     conn,
+		registry: registry,
+		ctx:      ctx,
+		cancel:   cancel,
+		pending:  make(map[uint32]chan result),
+		done:     make(chan struct{}),
+	}
+	go c.reader()
+	return c
+}
+
+// Call 调用对端的 method, 阻塞直到收到响应、ctx 结束或者连接关闭.
+// 对端 Handler 返回的错误为 *RemoteError, ctx 结束时返回 ctx.Err()
+func (c *Conn) Call(ctx context.Context, method string, req []byte) ([]byte, error) {
+	seq := c.seq.Add(1)
+	ch := make(chan result, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.pending[seq] = ch
+	c.mu.Unlock()
+
+	w := packet2.WriterP(headSize + 2 + len(method) + len(req))
+	w.WriteInt8(kindRequest)
+	w.WriteUint32(seq)
+	w.WriteString(method)
+	w.Write(req)
+	err := c.conn.Send(w.Data())
+	packet2.Return(w)
+	if err != nil {
+		c.remove(seq)
+		return nil, err
+	}
+
+	select {
+	case r := <-ch:
+		return r.resp, r.err
+	case <-ctx.Done():
+		c.remove(seq)
+		return nil, ctx.Err()
+	}
+}
+
+// Done 在连接关闭后关闭
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err 返回连接关闭的原因, 连接未关闭时返回 nil
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) reader() {
+	defer utils.RecoverPanic()
+	for {
+		msg, err := transport.RecvMessage(context.Background(), c.conn)
+		if err != nil {
+			c.onClose(err)
+			return
+		}
+		in := msg.Data
+		if len(in) < headSize {
+			msg.Release()
+			continue
+		}
+
+		kind := int8(in[0])
+		seq := binary.BigEndian.Uint32(in[1:headSize])
+		body := in[headSize:]
+		switch kind {
+		case kindRequest:
+			//请求在处理协程中释放
+			c.onRequest(seq, body, msg)
+			continue
+		case kindResponse:
+			//响应由调用方持有, 拷贝后释放接收缓冲区
+			c.complete(seq, result{resp: append([]byte(nil), body...)})
+		case kindError:
+			c.complete(seq, result{err: &RemoteError{Message: string(body)}})
+		}
+		msg.Release()
+	}
+}
+
+// onRequest 处理请求, msg 为请求所在的消息, 处理完毕后释放
+func (c *Conn) onRequest(seq uint32, body []byte, msg network.Message) {
+	if len(body) < 2 {
+		msg.Release()
+		return
+	}
+	size := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+size {
+		msg.Release()
+		return
+	}
+	method := string(body[2 : 2+size])
+	req := body[2+size:]
+
+	handler := c.registry.get(method)
+	if handler == nil {
+		msg.Release()
+		c.reply(seq, nil, MethodNotFound(method))
+		return
+	}
+
+	utils.GoRecoverPanic(func() {
+		defer msg.Release()
+		resp, err := handler(c.ctx, req)
+		c.reply(seq, resp, err)
+	})
+}
+
+func (c *Conn) reply(seq uint32, resp []byte, err error) {
+	kind := kindResponse
+	if err != nil {
+		kind = kindError
+		resp = []byte(err.Error())
+	}
+
+	w := packet2.WriterP(headSize + len(resp))
+	w.WriteInt8(kind)
+	w.WriteUint32(seq)
+	w.Write(resp)
+	_ = c.conn.Send(w.Data())
+	packet2.Return(w)
+}
+
+// complete 投递响应, 已超时或者被取消的调用的响应被丢弃
+func (c *Conn) complete(seq uint32, r result) {
+	c.mu.Lock()
+	ch, ok := c.pending[seq]
+	delete(c.pending, seq)
+	c.mu.Unlock()
+	if ok {
+		ch <- r
+	}
+}
+
+func (c *Conn) remove(seq uint32) {
+	c.mu.Lock()
+	delete(c.pending, seq)
+	c.mu.Unlock()
+}
+
+// onClose 连接关闭时以关闭原因结束所有未完成的调用
+func (c *Conn) onClose(err error) {
+	c.mu.Lock()
+	c.err = err
+	pending := c.pending
+	c.pending = make(map[uint32]chan result)
+	c.mu.Unlock()
+
+	for _, ch := range pending {
+		ch <- result{err: err}
+	}
+	c.cancel()
+	close(c.done)
+}
@@ -0,0 +1,115 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: rpc_test
+   @2026 10月 周日 21:50
+*/
+
+func Test_Call(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("echo", func(ctx context.Context, req []byte) ([]byte, error) {
+		return req, nil
+	})
+	registry.Register("fail", func(ctx context.Context, req []byte) ([]byte, error) {
+		return nil, errors.New("bad request")
+	})
+	server := serveRpc(t, registry)
+	defer server.Stop()
+
+	c := dialRpc(server.Addr())
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		resp, err := c.Call(ctx, "echo", []byte("hello"))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(resp))
+	}
+
+	_, err := c.Call(ctx, "fail", nil)
+	assert.True(t, IsRemoteError(err))
+	assert.Contains(t, err.Error(), "bad request")
+
+	_, err = c.Call(ctx, "unknown", nil)
+	assert.True(t, IsRemoteError(err))
+	assert.Contains(t, err.Error(), "method not found")
+}
+
+func Test_CallTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("block", func(ctx context.Context, req []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	server := serveRpc(t, registry)
+	defer server.Stop()
+
+	c := dialRpc(server.Addr())
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	_, err := c.Call(ctx, "block", nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_CallConnClosed(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("block", func(ctx context.Context, req []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	server := serveRpc(t, registry)
+
+	c := dialRpc(server.Addr())
+	defer c.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := c.Call(context.Background(), "block", nil)
+		errCh <- err
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	//GracefulStop 通知客户端关闭连接
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		_ = server.GracefulStop(ctx)
+	}()
+
+	select {
+	case err := <-errCh:
+		assert.Error(t, err)
+		assert.False(t, IsRemoteError(err))
+	case <-time.After(time.Second * 5):
+		t.Fatal("pending call not failed after conn closed")
+	}
+	<-c.Done()
+	_, err := c.Call(context.Background(), "block", nil)
+	assert.Equal(t, c.Err(), err)
+}
+
+func serveRpc(t *testing.T, registry *Registry) transport.IServer {
+	server, err := transport.Serve("tcp", "127.0.0.1:0", func(conn transport.IConn) {
+		<-NewConn(conn, registry).Done()
+	})
+	assert.NoError(t, err)
+	return server
+}
+
+func dialRpc(addr string) *Conn {
+	return NewConn(transport.DialWithOps(context.Background(), addr), nil)
+}
@@ -0,0 +1,215 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/orbit-w/meteor/modules/wrappers/sender_wrapper"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: backpressure_test
+   @2026 10月 周日 10:48
+*/
+
+// gatedBuffer 发送函数阻塞在 gate 上的 ControlBuffer, 用于模拟写不出去的慢连接
+type gatedBuffer struct {
+	*ControlBuffer
+	gate chan struct{}
+	sent chan string
+}
+
+func newGatedBuffer(op network.SendQueueOptions, onSlow func()) *gatedBuffer {
+	g := &gatedBuffer{
+		ControlBuffer: new(ControlBuffer),
+		gate:          make(chan struct{}),
+		sent:          make(chan string, 16),
+	}
+	BuildControlBuffer(g.ControlBuffer, MaxIncomingPacket)
+	g.SetSendQueue(op, onSlow)
+	g.Run(sender_wrapper.NewSender(func(pack packet2.IPacket) error {
+		n := pack.Len()
+		<-g.gate
+		r := packet2.ReaderP(pack.Data())
+		for len(r.Remain()) > 0 {
+			data, _ := r.ReadBytes32()
+			g.sent <- string(data)
+		}
+		packet2.Return(pack)
+		g.OnSent(n)
+		return nil
+	}))
+	return g
+}
+
+func (g *gatedBuffer) recv(t *testing.T) string {
+	select {
+	case data := <-g.sent:
+		return data
+	case <-time.After(time.Second * 5):
+		t.Fatal("recv timeout")
+		return ""
+	}
+}
+
+func message(b byte) []byte {
+	data := make([]byte, 40)
+	for i := range data {
+		data[i] = b
+	}
+	return data
+}
+
+func Test_SendQueueReject(t *testing.T) {
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 100}, nil)
+	defer g.OnClose()
+
+	assert.NoError(t, g.Set(message('a')))
+	assert.NoError(t, g.Set(message('b')))
+	assert.ErrorIs(t, g.Set(message('c')), ErrSendQueueFull)
+	assert.Equal(t, 88, g.Pending())
+
+	close(g.gate)
+	assert.Equal(t, string(message('a')), g.recv(t))
+	assert.Equal(t, string(message('b')), g.recv(t))
+	assert.Eventually(t, func() bool { return g.Pending() == 0 }, time.Second*5, time.Millisecond*10)
+	assert.NoError(t, g.Set(message('c')))
+}
+
+func Test_SendQueueBlock(t *testing.T) {
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 100, Policy: network.QueuePolicyBlock}, nil)
+	defer g.OnClose()
+
+	assert.NoError(t, g.Set(message('a')))
+	assert.NoError(t, g.Set(message('b')))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	assert.ErrorIs(t, g.SetContext(ctx, message('c')), context.DeadlineExceeded)
+	//广播不会被慢连接阻塞
+	assert.ErrorIs(t, g.SetEncoded(append([]byte{0, 0, 0, 40}, message('c')...)), ErrSendQueueFull)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- g.SetContext(context.Background(), message('c'))
+	}()
+	select {
+	case <-done:
+		t.Fatal("send should block above high watermark")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(g.gate)
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("blocked send not woken")
+	}
+	assert.Equal(t, string(message('a')), g.recv(t))
+	assert.Equal(t, string(message('b')), g.recv(t))
+	assert.Equal(t, string(message('c')), g.recv(t))
+}
+
+func Test_SendQueueBlockClose(t *testing.T) {
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 100, Policy: network.QueuePolicyBlock}, nil)
+	defer close(g.gate)
+
+	assert.NoError(t, g.Set(message('a')))
+	assert.NoError(t, g.Set(message('b')))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Set(message('c'))
+	}()
+	time.Sleep(time.Millisecond * 50)
+	g.OnClose()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrDisconnected)
+	case <-time.After(time.Second * 5):
+		t.Fatal("blocked send not woken by close")
+	}
+}
+
+func Test_SendQueueDropOldest(t *testing.T) {
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 100, Policy: network.QueuePolicyDropOldest}, nil)
+	defer g.OnClose()
+
+	//a 已交给发送协程, b 留在队列中被 c 挤掉
+	assert.NoError(t, g.Set(message('a')))
+	assert.Eventually(t, func() bool { return g.Pending() == 44 }, time.Second*5, time.Millisecond*10)
+	assert.NoError(t, g.Set(message('b')))
+	assert.NoError(t, g.Set(message('c')))
+	assert.Equal(t, 88, g.Pending())
+
+	close(g.gate)
+	assert.Equal(t, string(message('a')), g.recv(t))
+	assert.Equal(t, string(message('c')), g.recv(t))
+}
+
+func Test_SendQueueDisconnect(t *testing.T) {
+	slow := make(chan struct{}, 1)
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 100, Policy: network.QueuePolicyDisconnect}, func() {
+		slow <- struct{}{}
+	})
+	defer close(g.gate)
+	defer g.OnClose()
+
+	assert.NoError(t, g.Set(message('a')))
+	assert.NoError(t, g.Set(message('b')))
+	assert.ErrorIs(t, g.Set(message('c')), ErrSlowConsumer)
+	select {
+	case <-slow:
+	default:
+		t.Fatal("onSlow not called")
+	}
+}
+
+func Test_SendQueueSlowConsumer(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.SendQueue = network.SendQueueOptions{
+		HighWatermark: 256 * 1024,
+		Policy:        network.QueuePolicyDisconnect,
+	}
+	result := make(chan [2]error, 1)
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		queued := conn.(IBackpressure)
+		data := make([]byte, 16*1024)
+		var (
+			sendErr error
+			peak    int
+		)
+		for sendErr == nil {
+			if sendErr = conn.Send(data); sendErr == nil {
+				peak = max(peak, queued.QueuedBytes())
+			}
+		}
+		assert.Greater(t, peak, conf.SendQueue.HighWatermark/2)
+		_, recvErr := conn.Recv(context.Background())
+		result <- [2]error{sendErr, recvErr}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	//从不读取的对端
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+
+	select {
+	case errs := <-result:
+		assert.ErrorIs(t, errs[0], ErrSlowConsumer)
+		assert.ErrorIs(t, errs[1], ErrSlowConsumer)
+	case <-time.After(time.Second * 10):
+		t.Fatal("slow consumer not disconnected")
+	}
+}
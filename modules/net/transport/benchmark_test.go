@@ -6,10 +6,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/orbit-w/meteor/modules/net/packet"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,3 +131,154 @@ func benchmarkEcho(b *testing.B, size, num int) {
 	wait()
 	runtime.GC()
 }
+
+func Benchmark_Recv64_Test(b *testing.B) {
+	benchmarkRecv(b, 64, false)
+}
+
+func Benchmark_RecvMessage64_Test(b *testing.B) {
+	benchmarkRecv(b, 64, true)
+}
+
+func Benchmark_Recv4K_Test(b *testing.B) {
+	benchmarkRecv(b, 4096, false)
+}
+
+func Benchmark_RecvMessage4K_Test(b *testing.B) {
+	benchmarkRecv(b, 4096, true)
+}
+
+// benchmarkRecv 服务端推送 b.N 条消息, 客户端通过 Recv 或者 RecvMessage(池化缓冲区)接收
+func benchmarkRecv(b *testing.B, size int, pooled bool) {
+	var (
+		buf = make([]byte, size)
+		ctx = context.Background()
+	)
+
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		for i := 0; i < b.N; i++ {
+			if err := conn.Send(buf); err != nil {
+				return
+			}
+		}
+		_, _ = conn.Recv(ctx)
+	}, DefaultServerConfig())
+	assert.NoError(b, err)
+	defer server.Stop()
+
+	conn := DialWithOps(ctx, server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+	receiver := conn.(IMessageReceiver)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if pooled {
+			msg, err := receiver.RecvMessage(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			msg.Release()
+		} else {
+			if _, err := conn.Recv(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Benchmark_Receiver4K_Test(b *testing.B) {
+	benchmarkReceiver(b, 4096, false)
+}
+
+func Benchmark_ReceiverMessage4K_Test(b *testing.B) {
+	benchmarkReceiver(b, 4096, true)
+}
+
+// benchmarkReceiver 只测量接收队列: 每条消息读入池化的缓冲区后投递, Recv 拷贝消息后归还缓冲区,
+// RecvMessage 在 Release 时归还, 两者的缓冲区都被复用
+func benchmarkReceiver(b *testing.B, size int, pooled bool) {
+	ctx := context.Background()
+	r := network.NewBlockReceiver()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := packet.GetBuffer(size)
+		r.PutMessage(network.NewMessage(buf.Bytes(), buf))
+		if pooled {
+			msg, err := r.RecvMessage(ctx)
+			if err != nil {
+				b.Fatal(err)
+			}
+			msg.Release()
+		} else {
+			if _, err := r.Recv(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func Benchmark_WriteEncode_Test(b *testing.B) {
+	benchmarkWrite(b, false)
+}
+
+func Benchmark_WriteVector_Test(b *testing.B) {
+	benchmarkWrite(b, true)
+}
+
+// benchmarkWrite 对比发送路径: 每个批次重新编码后单独 Write, 与多个批次合并为一次 writev
+func benchmarkWrite(b *testing.B, vector bool) {
+	for _, size := range []int{256, 4096, 65536} {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			assert.NoError(b, err)
+			defer l.Close()
+			go func() {
+				peer, err := l.Accept()
+				if err != nil {
+					return
+				}
+				_, _ = io.Copy(io.Discard, peer)
+			}()
+			conn, err := net.Dial("tcp", l.Addr().String())
+			assert.NoError(b, err)
+			defer conn.Close()
+
+			codec := network.NewCodec(MaxIncomingPacket, false, 0)
+			vw := newVectorWriter(codec)
+			batch := make([]byte, size)
+			packs := make([]packet.IPacket, MaxCoalesce)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size * MaxCoalesce))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := range packs {
+					packs[j] = packet.ReaderP(batch)
+				}
+				if vector {
+					if _, err = vw.write(conn, packs, WriteTimeout); err != nil {
+						b.Fatal(err)
+					}
+				} else {
+					for _, pack := range packs {
+						out, _ := codec.Encode(pack.Data(), network.TypeMessageRaw)
+						if _, err = conn.Write(out.Data()); err != nil {
+							b.Fatal(err)
+						}
+						packet.Return(out)
+					}
+				}
+				for _, pack := range packs {
+					packet.Return(pack)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"context"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+)
+
+/*
+   @Author: orbit-w
+   @File: chaos
+   @2026 10月 周日 21:40
+*/
+
+// chaosConn 以消息为单位注入故障的 IConn, 见 WrapChaosConn
+type chaosConn struct {
+	IConn
+	injector *network.ChaosInjector
+}
+
+// WrapChaosConn 使用 op 为 conn 注入消息级别的故障: Send 前等待时延、抖动与带宽限制, Recv 收到消息后等待带宽限制,
+// 截断的消息只保留前一部分, 重置时关闭连接并返回 network.ErrChaosReset; 不拆分消息.
+// 字节流级别的故障(拆分写入、截断帧)使用 Config.Chaos 或 WithChaos
+func WrapChaosConn(conn IConn, op *network.ChaosOptions) IConn {
+	return &chaosConn{
+		IConn:    conn,
+		injector: network.NewChaosInjector(op),
+	}
+}
+
+func (c *chaosConn) Send(data []byte) error {
+	if len(data) == 0 {
+		return c.IConn.Send(data)
+	}
+
+	action := c.injector.Next(network.ChaosOutbound, len(data))
+	if action.Reset {
+		_ = c.IConn.Close()
+		return network.ErrChaosReset
+	}
+	if action.Wait > 0 {
+		time.Sleep(action.Wait)
+	}
+	if action.Truncate > 0 {
+		data = data[:action.Truncate]
+	}
+	return c.IConn.Send(data)
+}
+
+func (c *chaosConn) Recv(ctx context.Context) ([]byte, error) {
+	in, err := c.IConn.Recv(ctx)
+	if err != nil || len(in) == 0 {
+		return in, err
+	}
+	return c.inject(ctx, in)
+}
+
+// RecvMessage 与 Recv 相同, 被包装的连接不支持池化接收时退化为 Recv
+func (c *chaosConn) RecvMessage(ctx context.Context) (network.Message, error) {
+	msg, err := RecvMessage(ctx, c.IConn)
+	if err != nil || len(msg.Data) == 0 {
+		return msg, err
+	}
+	in, err := c.inject(ctx, msg.Data)
+	if err != nil {
+		msg.Release()
+		return network.Message{}, err
+	}
+	msg.Data = in
+	return msg, nil
+}
+
+// inject 为收到的一条消息注入故障, 返回截断后的消息
+func (c *chaosConn) inject(ctx context.Context, in []byte) ([]byte, error) {
+	action := c.injector.Next(network.ChaosInbound, len(in))
+	if action.Reset {
+		_ = c.IConn.Close()
+		return nil, network.ErrChaosReset
+	}
+	if action.Truncate > 0 {
+		in = in[:action.Truncate]
+	}
+	if action.Wait > 0 {
+		timer := time.NewTimer(action.Wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return in, nil
+}
@@ -0,0 +1,157 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: chaos_test
+   @2026 10月 周日 22:10
+*/
+
+func Test_ChaosPartialWrites(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.Chaos = &network.ChaosOptions{
+		Seed:   1,
+		Stages: []network.ChaosStage{{ChaosFaults: network.ChaosFaults{PartialWriteRate: 0.5, Jitter: time.Millisecond}}},
+	}
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM), WithChaos(conf.Chaos))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//帧被拆分为多段到达时仍能正确重组
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	for i := 0; i < 50; i++ {
+		msg := fmt.Sprintf("message-%d", i)
+		assert.NoError(t, conn.Send([]byte(msg)))
+		in, err := conn.Recv(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, msg, string(in))
+	}
+}
+
+func Test_ChaosServerReset(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.Chaos = &network.ChaosOptions{
+		Stages: []network.ChaosStage{{At: time.Millisecond * 100, ChaosFaults: network.ChaosFaults{ResetRate: 1}}},
+	}
+	result := make(chan error, 1)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				result <- err
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("before")))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "before", string(in))
+
+	//进入重置阶段后的第一次读写重置连接
+	time.Sleep(time.Millisecond * 150)
+	assert.NoError(t, conn.Send([]byte("after")))
+	select {
+	case err = <-result:
+		assert.ErrorIs(t, err, network.ErrChaosReset)
+	case <-time.After(time.Second * 5):
+		t.Fatal("connection not reset")
+	}
+	_, err = conn.Recv(ctx)
+	assert.Error(t, err)
+}
+
+func Test_ChaosTruncate(t *testing.T) {
+	result := make(chan error, 1)
+	server, err := Serve("mem", "", func(conn IConn) {
+		_, err := conn.Recv(context.Background())
+		result <- err
+	})
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithChaos(&network.ChaosOptions{
+			Stages: []network.ChaosStage{{ChaosFaults: network.ChaosFaults{TruncateRate: 1}}},
+		}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//截断的帧不会被投递
+	assert.NoError(t, conn.Send([]byte("hello world")))
+	select {
+	case err = <-result:
+		assert.Error(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("truncated frame not detected")
+	}
+}
+
+func Test_WrapChaosConn(t *testing.T) {
+	server := serveMemEcho(t)
+	defer server.Stop()
+
+	raw := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM))
+	conn := WrapChaosConn(raw, &network.ChaosOptions{
+		Seed: 3,
+		Stages: []network.ChaosStage{
+			{ChaosFaults: network.ChaosFaults{TruncateRate: 1}},
+			{At: time.Millisecond * 100, ChaosFaults: network.ChaosFaults{ResetRate: 1}},
+		},
+	})
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	msg := "hello world"
+	assert.NoError(t, conn.Send([]byte(msg)))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Less(t, len(in), len(msg))
+	assert.True(t, strings.HasPrefix(msg, string(in)))
+
+	time.Sleep(time.Millisecond * 100)
+	assert.ErrorIs(t, conn.Send([]byte(msg)), network.ErrChaosReset)
+	_, err = raw.Recv(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: clock
+   @2026 10月 周日 19:05
+*/
+
+// Clock 内存连接模拟链路时延使用的时钟, 测试中替换为 VirtualClock 后由测试代码推进时间.
+// 只有链路时延经过 Clock; 读写超时、心跳与空闲检测仍然使用真实时间, 不能由 VirtualClock 驱动
+type Clock interface {
+	Now() time.Time
+	// AfterFunc 经过 d 之后调用 f
+	AfterFunc(d time.Duration, f func())
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+// VirtualClock 手动推进的虚拟时钟, 时间只在调用 Advance 时前进,
+// 到期的回调在 Advance 的调用协程中按到期时间顺序(相同时间按注册顺序)执行
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers timerHeap
+}
+
+type virtualTimer struct {
+	at  time.Time
+	seq uint64
+	f   func()
+}
+
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc d 不大于 0 时 f 在下一次 Advance 时执行
+func (c *VirtualClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	c.seq++
+	heap.Push(&c.timers, virtualTimer{at: c.now.Add(d), seq: c.seq, f: f})
+	c.mu.Unlock()
+}
+
+// Advance 将时间推进 d, 依次执行期间到期的回调, 回调中注册的到期回调同样会被执行
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(target) {
+		timer := heap.Pop(&c.timers).(virtualTimer)
+		if timer.at.After(c.now) {
+			c.now = timer.at
+		}
+		c.mu.Unlock()
+		timer.f()
+		c.mu.Lock()
+	}
+	c.now = target
+	c.mu.Unlock()
+}
+
+// Pending 尚未到期的回调数
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// timerHeap 以 (at, seq) 排序的最小堆
+type timerHeap []virtualTimer
+
+func (h timerHeap) Len() int {
+	return len(h)
+}
+
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *timerHeap) Push(x any) {
+	*h = append(*h, x.(virtualTimer))
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	n := len(old)
+	timer := old[n-1]
+	old[n-1] = virtualTimer{}
+	*h = old[:n-1]
+	return timer
+}
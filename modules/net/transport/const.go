@@ -2,6 +2,8 @@ package transport
 
 import (
 	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
 )
 
 /*
@@ -17,12 +19,28 @@ const (
 
 const (
 	BatchLimit   = 50
+	UrgentBurst  = 4 //bulk 通道有积压时最多连续发送的 urgent 批次数
 	PingTimeOut  = time.Second * 30
 	AckInterval  = time.Second * 10
 	MaxRetried   = 5
 	HeadLen      = 4 //包头字节数
 	ReadTimeout  = time.Second * 60
 	WriteTimeout = time.Second * 5
+
+	HandshakeTimeout = time.Second * 10
+)
+
+// 会话恢复
+const (
+	ResumeGracePeriod = time.Second * 30 //连接断开后会话保留的默认时长
+	ResumeWindowSize  = 512 << 10        //重放窗口默认保留的最大字节数
+	ResumeAckBytes    = 16 << 10         //接收方每收到该字节数的消息回复一次 SeqAck
+)
+
+// 客户端连接池
+const (
+	PoolSize     = 4  //每个地址默认保持的连接数
+	PoolReplicas = 64 //一致性哈希时每个地址默认的虚拟节点数
 )
 
 const (
@@ -33,8 +51,10 @@ const (
 )
 
 const (
-	TypeWorking = 1
-	TypeStopped = 2
+	TypeWorking  = 1
+	TypeStopped  = 2
+	TypePaused   = 3
+	TypeDraining = 4
 )
 
 const (
@@ -42,8 +62,25 @@ const (
 	cliStateStopped
 )
 
+// KCP 会话参数: nodelay 模式 + 快速重传, 保留拥塞控制以避免突发流量下 UDP 缓冲区溢出造成的停滞
+const (
+	KcpNoDelay      = 1
+	KcpInterval     = 10 //ms
+	KcpResend       = 2
+	KcpNoCongestion = 0
+	KcpSendWindow   = 1024
+	KcpRecvWindow   = 1024
+	KcpMtu          = 1400
+	KcpSockBuf      = 4 << 20 //UDP socket 读写缓冲区大小
+)
+
+const (
+	MaxDatagramSize = 65507 //单个 UDP 数据报的最大负载
+	MaxUdpSessions  = 65536 //UDP 服务端最多同时维护的虚拟连接数
+)
+
 const (
-	ZMinLen     = 200
+	ZMinLen     = network.CompressThreshold //默认压缩阈值, 长度小于该值的消息不压缩
 	GzippedSize = 1
 )
 
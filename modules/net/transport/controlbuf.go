@@ -1,11 +1,14 @@
 package transport
 
 import (
+	"context"
+	"sync"
+
 	"github.com/orbit-w/meteor/bases/misc/number_utils"
 	"github.com/orbit-w/meteor/bases/net/bigendian_buf"
+	"github.com/orbit-w/meteor/modules/net/network"
 	packet2 "github.com/orbit-w/meteor/modules/net/packet"
 	"github.com/orbit-w/meteor/modules/wrappers/sender_wrapper"
-	"sync"
 )
 
 /*
@@ -14,17 +17,40 @@ import (
    @2023 11月 周日 17:21
 */
 
+// Priority 消息的发送优先级
+type Priority int8
+
+const (
+	PriorityBulk   Priority = iota //普通消息(背包同步等大块数据), 默认
+	PriorityUrgent                 //紧急消息(战斗、控制类), flush 时优先发送
+	priorityCount
+)
+
+// sendLane 一个优先级的发送队列, 消息连续存放: size<int32> | data
+type sendLane struct {
+	length int
+	buffer *bigendian_buf.BigEndianPacket
+}
+
+// ControlBuffer 连接的发送队列, 每个优先级一条通道, 同一通道内的消息保持顺序, 不同通道之间不保证顺序
 type ControlBuffer struct {
 	consumerWaiting bool
 	state           int8
 	max             uint32
-	length          int
-	buffer          *bigendian_buf.BigEndianPacket
+	starved         int //bulk 通道有积压时连续发送的 urgent 批次数
+	pending         int //队列中待发送的字节数, 包括 item 的长度前缀
+	inflight        int //已交给 SenderWrapper 但未写入连接的字节数
+	maxPending      int //未处于发送状态(连接建立前或断线重连期间)时允许缓存的最大字节数, 0 表示不限制
+	queue           network.SendQueueOptions
+	onSlow          func()        //QueuePolicyDisconnect 下积压超过高水位时回调, 由连接负责关闭
+	writable        chan struct{} //QueuePolicyBlock 下阻塞的 Send 等待积压降到低水位以下
+	lanes           [priorityCount]sendLane
 	mu              sync.Mutex
 	sw              *sender_wrapper.SenderWrapper
 
 	ch    chan struct{}
 	close chan struct{}
+	done  chan struct{}
 }
 
 func NewControlBuffer(max uint32, _sw *sender_wrapper.SenderWrapper) *ControlBuffer {
@@ -32,13 +58,14 @@ func NewControlBuffer(max uint32, _sw *sender_wrapper.SenderWrapper) *ControlBuf
 		state:           TypeWorking,
 		consumerWaiting: false,
 		max:             max,
-		buffer:          bigendian_buf.New(),
 		mu:              sync.Mutex{},
 		ch:              make(chan struct{}, 1),
 		close:           make(chan struct{}, 1),
+		done:            make(chan struct{}),
 		sw:              _sw,
 	}
-	go ins.flush()
+	ins.initLanes()
+	go ins.flush(ins.close, ins.done)
 	ins.Kick()
 	return ins
 }
@@ -46,10 +73,16 @@ func NewControlBuffer(max uint32, _sw *sender_wrapper.SenderWrapper) *ControlBuf
 func BuildControlBuffer(buf *ControlBuffer, max uint32) {
 	buf.max = max
 	buf.ch = make(chan struct{}, 1)
-	buf.buffer = bigendian_buf.New()
+	buf.initLanes()
 	buf.mu = sync.Mutex{}
 }
 
+func (ins *ControlBuffer) initLanes() {
+	for i := range ins.lanes {
+		ins.lanes[i].buffer = bigendian_buf.New()
+	}
+}
+
 func (ins *ControlBuffer) Run(_sw *sender_wrapper.SenderWrapper) {
 	ins.mu.Lock()
 	if ins.state == TypeStopped {
@@ -58,13 +91,90 @@ func (ins *ControlBuffer) Run(_sw *sender_wrapper.SenderWrapper) {
 	}
 	ins.sw = _sw
 	ins.close = make(chan struct{}, 1)
+	ins.done = make(chan struct{})
 	ins.state = TypeWorking
+	go ins.flush(ins.close, ins.done)
 	ins.mu.Unlock()
 
-	go ins.flush()
 	ins.Kick()
 }
 
+// Pause 停止 flush 协程并关闭当前的 SenderWrapper, 已缓存但未发送的数据保留到下一次 Run.
+// 暂停期间 Set 仍然可以写入, 但受 maxPending 限制
+func (ins *ControlBuffer) Pause() {
+	ins.mu.Lock()
+	if ins.state != TypeWorking {
+		ins.mu.Unlock()
+		return
+	}
+	ins.state = TypePaused
+	ins.wakeWriters()
+	close(ins.close)
+	done := ins.done
+	ins.mu.Unlock()
+	<-done
+}
+
+// Drain 停止接收新数据, 将已缓存的数据全部交给 SenderWrapper 后关闭 flush 协程与 SenderWrapper,
+// 阻塞直到 flush 协程退出
+func (ins *ControlBuffer) Drain() {
+	ins.mu.Lock()
+	if ins.state != TypeWorking {
+		ins.mu.Unlock()
+		return
+	}
+	ins.state = TypeDraining
+	ins.wakeWriters()
+	close(ins.close)
+	done := ins.done
+	ins.mu.Unlock()
+	<-done
+}
+
+// SetMaxPending 设置未处于发送状态时允许缓存的最大字节数, 超出后 Set 返回 ErrSendQueueFull
+func (ins *ControlBuffer) SetMaxPending(max int) {
+	ins.mu.Lock()
+	ins.maxPending = max
+	ins.mu.Unlock()
+}
+
+// SetSendQueue 设置发送状态下的高低水位与背压策略, onSlow 在 QueuePolicyDisconnect 触发时调用
+func (ins *ControlBuffer) SetSendQueue(op network.SendQueueOptions, onSlow func()) {
+	ins.mu.Lock()
+	ins.queue = op
+	ins.onSlow = onSlow
+	ins.mu.Unlock()
+}
+
+// Pending 返回积压的字节数: 队列中待发送的数据以及已交给 SenderWrapper 但未写入连接的数据
+func (ins *ControlBuffer) Pending() int {
+	ins.mu.Lock()
+	defer ins.mu.Unlock()
+	return ins.queued()
+}
+
+// OnSent 由 SenderWrapper 的发送函数在批次写入(或丢弃)后调用, n 为批次的字节数
+func (ins *ControlBuffer) OnSent(n int) {
+	ins.mu.Lock()
+	ins.inflight -= n
+	if ins.writable != nil && ins.queued() <= ins.queue.Low() {
+		ins.wakeWriters()
+	}
+	var kick bool
+	if !ins.throttled() && ins.consumerWaiting && !ins.isEmpty() &&
+		(ins.state == TypeWorking || ins.state == TypeDraining) {
+		kick = true
+		ins.consumerWaiting = false
+	}
+	ins.mu.Unlock()
+	if kick {
+		select {
+		case ins.ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func (ins *ControlBuffer) Kick() {
 	var kick bool
 	ins.mu.Lock()
@@ -83,14 +193,94 @@ func (ins *ControlBuffer) Kick() {
 }
 
 func (ins *ControlBuffer) Set(data []byte) error {
+	return ins.put(context.Background(), data, false, PriorityBulk)
+}
+
+// SetContext 与 Set 相同, QueuePolicyBlock 下阻塞等待时 ctx 结束返回 ctx.Err()
+func (ins *ControlBuffer) SetContext(ctx context.Context, data []byte) error {
+	return ins.put(ctx, data, false, PriorityBulk)
+}
+
+// SetWithPriority 将 data 写入 p 对应的通道
+func (ins *ControlBuffer) SetWithPriority(data []byte, p Priority) error {
+	if p < PriorityBulk || p >= priorityCount {
+		p = PriorityBulk
+	}
+	return ins.put(context.Background(), data, false, p)
+}
+
+// SetEncoded 写入已编码的消息 item: size<int32> | data, 用于广播时只编码一次.
+// 广播不能被单个慢连接阻塞, QueuePolicyBlock 下积压超过高水位时直接返回 ErrSendQueueFull
+func (ins *ControlBuffer) SetEncoded(item []byte) error {
+	return ins.put(nil, item, true, PriorityBulk)
+}
+
+func (ins *ControlBuffer) put(ctx context.Context, data []byte, encoded bool, p Priority) error {
+	size := len(data)
+	if !encoded {
+		size += 4
+	}
+
 	ins.mu.Lock()
-	if ins.state == TypeStopped {
-		ins.mu.Unlock()
-		return ErrDisconnected
+	for {
+		if ins.state == TypeStopped || ins.state == TypeDraining {
+			ins.mu.Unlock()
+			return ErrDisconnected
+		}
+		if ins.state != TypeWorking {
+			if ins.maxPending > 0 && ins.pending+size > ins.maxPending {
+				ins.mu.Unlock()
+				return ErrSendQueueFull
+			}
+			break
+		}
+		if !ins.overflow(size) {
+			break
+		}
+
+		switch ins.queue.Policy {
+		case network.QueuePolicyDropOldest:
+			ins.dropOldest(size)
+		case network.QueuePolicyDisconnect:
+			onSlow := ins.onSlow
+			ins.mu.Unlock()
+			if onSlow != nil {
+				onSlow()
+			}
+			return ErrSlowConsumer
+		case network.QueuePolicyBlock:
+			if ctx == nil {
+				ins.mu.Unlock()
+				return ErrSendQueueFull
+			}
+			if ins.writable == nil {
+				ins.writable = make(chan struct{})
+			}
+			writable := ins.writable
+			ins.mu.Unlock()
+			select {
+			case <-writable:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			ins.mu.Lock()
+			continue
+		default:
+			ins.mu.Unlock()
+			return ErrSendQueueFull
+		}
+		break
 	}
+
 	var kick bool
-	ins.length++
-	ins.buffer.WriteBytes32(data)
+	lane := &ins.lanes[p]
+	lane.length++
+	ins.pending += size
+	if encoded {
+		lane.buffer.Write(data)
+	} else {
+		lane.buffer.WriteBytes32(data)
+	}
 	if ins.consumerWaiting {
 		kick = true
 		ins.consumerWaiting = false
@@ -109,65 +299,164 @@ func (ins *ControlBuffer) Set(data []byte) error {
 func (ins *ControlBuffer) OnClose() {
 	ins.mu.Lock()
 	defer ins.mu.Unlock()
-	if ins.state == TypeWorking {
+	switch ins.state {
+	case TypeWorking:
 		ins.state = TypeStopped
+		ins.wakeWriters()
 		if ins.close != nil {
 			close(ins.close)
 		}
+	case TypeStopped:
+	case TypeDraining:
+		//flush 协程退出时释放
+		ins.state = TypeStopped
+	default:
+		//flush 协程未运行, 直接释放
+		ins.state = TypeStopped
+		ins.wakeWriters()
+		ins.release()
 	}
 }
 
-func (ins *ControlBuffer) flush() {
+func (ins *ControlBuffer) flush(closeCh, done chan struct{}) {
 	defer func() {
 		if x := recover(); x != nil {
 		}
-		ins.safeReturn()
+		ins.safeReturn(done)
 	}()
 
-	var (
-		writer = bigendian_buf.NewWithInitialSize(2048)
-	)
-
 FLUSH:
 	ins.mu.Lock()
-	for ins.state == TypeWorking && !ins.isEmpty() {
-		size := number_utils.Min[int](BatchLimit, ins.length)
+	for (ins.state == TypeWorking || ins.state == TypeDraining) && !ins.isEmpty() && !ins.throttled() {
+		lane := ins.nextLane()
+		size := number_utils.Min[int](BatchLimit, lane.length)
+		//通道中的消息连续存放: size<int32> | data, 一个批次即为 Remain 的前 n 个字节, 只拷贝一次
+		batch := lane.buffer.Remain()
+		n := 0
 		for i := 0; i < size; i++ {
-			length, _ := ins.buffer.NextBytesSize32()
-			if uint32(writer.Len())+uint32(length)+4 > ins.max {
+			length, _ := lane.buffer.NextBytesSize32()
+			if uint32(n)+uint32(length)+4 > ins.max {
 				break
 			}
-			ins.length--
-			data, _ := ins.buffer.ReadBytes32()
-			writer.WriteBytes32(data)
+			lane.length--
+			_, _ = lane.buffer.ReadBytes32()
+			n += length + 4
 		}
 
-		w := packet2.ReaderP(writer.Data())
-		writer.Reset()
-		_ = ins.sw.Send(w)
+		ins.pending -= n
+		ins.inflight += n
+		_ = ins.sw.Send(packet2.ReaderP(batch[:n]))
 	}
 
 	ins.consumerWaiting = true
-	ins.buffer.Reset()
+	for i := range ins.lanes {
+		if ins.lanes[i].length == 0 {
+			ins.lanes[i].buffer.Reset()
+		}
+	}
 	ins.mu.Unlock()
 	select {
 	case <-ins.ch:
 		goto FLUSH
-	case <-ins.close:
+	case <-closeCh:
+		ins.mu.Lock()
+		draining := ins.state == TypeDraining && !ins.isEmpty()
+		throttled := ins.throttled()
+		ins.mu.Unlock()
+		if draining {
+			if throttled {
+				//等待 OnSent 唤醒
+				<-ins.ch
+			}
+			goto FLUSH
+		}
 		return
 	}
 }
 
-func (ins *ControlBuffer) safeReturn() {
+func (ins *ControlBuffer) safeReturn(done chan struct{}) {
 	ins.mu.Lock()
 	defer ins.mu.Unlock()
-	ins.state = TypeStopped
+	defer close(done)
 	ins.sw.OnClose()
-	ins.buffer.Free()
+	if ins.state == TypePaused {
+		return
+	}
+	ins.state = TypeStopped
+	ins.release()
+}
+
+func (ins *ControlBuffer) release() {
+	for i := range ins.lanes {
+		lane := &ins.lanes[i]
+		if lane.buffer != nil {
+			lane.buffer.Free()
+			lane.buffer = nil
+		}
+	}
 	close(ins.ch)
-	ins.buffer = nil
 }
 
 func (ins *ControlBuffer) isEmpty() bool {
-	return ins.length == 0
+	return ins.lanes[PriorityBulk].length == 0 && ins.lanes[PriorityUrgent].length == 0
+}
+
+// nextLane 选择下一个批次的通道: 优先发送 urgent 通道, bulk 通道有积压且已连续发送 UrgentBurst 个 urgent 批次时
+// 发送一个 bulk 批次, 防止 bulk 通道饿死
+func (ins *ControlBuffer) nextLane() *sendLane {
+	urgent, bulk := &ins.lanes[PriorityUrgent], &ins.lanes[PriorityBulk]
+	if urgent.length == 0 || (bulk.length > 0 && ins.starved >= UrgentBurst) {
+		ins.starved = 0
+		return bulk
+	}
+	if bulk.length > 0 {
+		ins.starved++
+	}
+	return urgent
+}
+
+func (ins *ControlBuffer) queued() int {
+	return ins.pending + ins.inflight
+}
+
+// throttled 限制交给 SenderWrapper 的数据量, 积压的数据保留在队列中, 使得优先级、高水位与丢弃策略作用于
+// 真实的积压而不是 SenderWrapper 的无界队列: 设置了高水位时同一时间只有一个批次, 否则不超过 max 字节
+func (ins *ControlBuffer) throttled() bool {
+	if ins.queue.HighWatermark > 0 {
+		return ins.inflight > 0
+	}
+	return ins.inflight >= int(ins.max)
+}
+
+// overflow 写入 size 字节后积压是否超过高水位, 队列为空时总是允许写入, 避免超过高水位的单条消息无法发送
+func (ins *ControlBuffer) overflow(size int) bool {
+	high := ins.queue.HighWatermark
+	if high <= 0 {
+		return false
+	}
+	queued := ins.queued()
+	return queued > 0 && queued+size > high
+}
+
+// dropOldest 丢弃队列中最早的消息直到写入 size 字节后不超过高水位或者队列为空, 先丢弃 bulk 通道
+func (ins *ControlBuffer) dropOldest(size int) {
+	for _, p := range [...]Priority{PriorityBulk, PriorityUrgent} {
+		lane := &ins.lanes[p]
+		for lane.length > 0 && ins.overflow(size) {
+			data, err := lane.buffer.ReadBytes32()
+			if err != nil {
+				return
+			}
+			lane.length--
+			ins.pending -= len(data) + 4
+		}
+	}
+}
+
+// wakeWriters 唤醒所有阻塞在高水位上的 Send, 调用方需持有 mu
+func (ins *ControlBuffer) wakeWriters() {
+	if ins.writable != nil {
+		close(ins.writable)
+		ins.writable = nil
+	}
 }
@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"net"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: encrypt
+   @2026 10月 周日 20:40
+*/
+
+// keyExchangeMaxSize 密钥交换帧的最大长度: compressor<uint8> | type<int8> | suite<uint8> | public key<32 bytes>
+const keyExchangeMaxSize = 64
+
+// serverKeyExchange 读取客户端的密钥交换帧并回复服务端公钥, 成功后为 codec 设置协商出的 Cipher
+func serverKeyExchange(conn net.Conn, codec *mnetwork.Codec, head, body []byte, op *mnetwork.EncryptionOptions) error {
+	hc := mnetwork.NewCodec(uint32(len(body)), false, HandshakeTimeout)
+	data, h, err := hc.BlockDecodeBody(conn, head, body)
+	if err != nil {
+		return err
+	}
+	if h != mnetwork.TypeMessageKeyExchange {
+		return mnetwork.ErrKeyExchangeFailed
+	}
+	suite, clientPub, err := mnetwork.DecodeKeyExchange(data)
+	if err != nil {
+		return err
+	}
+
+	kx, err := mnetwork.NewKeyExchange(suite, op.PrivateKey)
+	if err != nil {
+		return err
+	}
+	cipher, err := kx.ServerCipher(clientPub)
+	if err != nil {
+		return err
+	}
+	if err = writeKeyExchange(conn, hc, kx); err != nil {
+		return err
+	}
+	codec.SetCipher(cipher)
+	return nil
+}
+
+// clientKeyExchange 发送客户端公钥并等待服务端回复, 成功后为 codec 设置协商出的 Cipher
+func clientKeyExchange(conn net.Conn, codec *mnetwork.Codec, op *mnetwork.EncryptionOptions) error {
+	kx, err := mnetwork.NewKeyExchange(op.Suite, nil)
+	if err != nil {
+		return err
+	}
+	hc := mnetwork.NewCodec(keyExchangeMaxSize, false, HandshakeTimeout)
+	if err = writeKeyExchange(conn, hc, kx); err != nil {
+		return err
+	}
+
+	data, h, err := hc.BlockDecodeBody(conn, make([]byte, HeadLen), make([]byte, keyExchangeMaxSize))
+	if err != nil {
+		return err
+	}
+	if h != mnetwork.TypeMessageKeyExchange {
+		return mnetwork.ErrKeyExchangeFailed
+	}
+	suite, serverPub, err := mnetwork.DecodeKeyExchange(data)
+	if err != nil {
+		return err
+	}
+	if suite != op.Suite {
+		return mnetwork.ErrUnsupportedCipher
+	}
+	if err = mnetwork.VerifyPeerPublicKey(op.PeerPublicKey, serverPub); err != nil {
+		return err
+	}
+
+	cipher, err := kx.ClientCipher(serverPub)
+	if err != nil {
+		return err
+	}
+	codec.SetCipher(cipher)
+	return nil
+}
+
+func writeKeyExchange(conn net.Conn, hc *mnetwork.Codec, kx *mnetwork.KeyExchange) error {
+	pack := hc.EncodeBody(kx.Encode(), mnetwork.TypeMessageKeyExchange)
+	defer packet2.Return(pack)
+	if err := conn.SetWriteDeadline(time.Now().Add(HandshakeTimeout)); err != nil {
+		return err
+	}
+	_, err := conn.Write(pack.Data())
+	return err
+}
@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: encrypt_test
+   @2026 10月 周日 21:05
+*/
+
+func Test_EncryptEcho(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	conf := DefaultServerConfig()
+	conf.Encryption = &network.EncryptionOptions{PrivateKey: priv}
+
+	for protocol, serve := range map[network.Protocol]func(*testing.T, *Config) IServer{
+		network.TCP: serveEcho,
+		network.WS:  serveWsEcho,
+	} {
+		server := serve(t, conf)
+		for _, suite := range []network.CipherSuite{network.CipherAES256GCM, network.CipherChaCha20Poly1305} {
+			conn := DialWithOps(context.Background(), server.Addr(),
+				WithProtocol(protocol),
+				WithEncryption(&network.EncryptionOptions{
+					Suite:         suite,
+					PeerPublicKey: priv.PublicKey().Bytes(),
+				}))
+			for i := 0; i < 10; i++ {
+				assertEcho(t, conn, "hello, encrypted world")
+			}
+			_ = conn.Close()
+		}
+		_ = server.Stop()
+	}
+}
+
+func Test_EncryptPeerKeyMismatch(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	conf := DefaultServerConfig()
+	conf.Encryption = &network.EncryptionOptions{PrivateKey: priv}
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+
+	codec := network.NewCodec(MaxIncomingPacket, false, 0)
+	err = clientKeyExchange(raw, codec, &network.EncryptionOptions{
+		Suite:         network.CipherAES256GCM,
+		PeerPublicKey: other.PublicKey().Bytes(),
+	})
+	assert.ErrorIs(t, err, network.ErrPeerKeyMismatch)
+}
+
+func Test_EncryptRejectPlaintext(t *testing.T) {
+	received := make(chan []byte, 1)
+	conf := DefaultServerConfig()
+	conf.Encryption = &network.EncryptionOptions{}
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		in, err := conn.Recv(context.Background())
+		if err == nil {
+			received <- in
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+
+	codec := network.NewCodec(MaxIncomingPacket, false, 0)
+	assert.NoError(t, clientKeyExchange(raw, codec, &network.EncryptionOptions{Suite: network.CipherChaCha20Poly1305}))
+
+	//密钥交换完成后发送明文数据帧, 服务端关闭连接
+	plain := network.NewCodec(MaxIncomingPacket, false, 0)
+	w := packet2.WriterP(64)
+	w.WriteBytes32([]byte("plaintext"))
+	pack, err := plain.Encode(w.Data(), network.TypeMessageRaw)
+	assert.NoError(t, err)
+	packet2.Return(w)
+	_, err = raw.Write(pack.Data())
+	assert.NoError(t, err)
+	packet2.Return(pack)
+
+	_ = raw.SetReadDeadline(time.Now().Add(time.Second * 5))
+	_, err = raw.Read(make([]byte, 1))
+	assert.Error(t, err)
+	select {
+	case in := <-received:
+		t.Fatalf("unexpected plaintext message: %s", in)
+	default:
+	}
+}
+
+func Test_EncryptRejectPlaintextControl(t *testing.T) {
+	for _, h := range []int8{network.TypeMessageClose, network.TypeMessageSeqAck} {
+		errCh := make(chan error, 1)
+		conf := DefaultServerConfig()
+		conf.Encryption = &network.EncryptionOptions{}
+		server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+			_, err := conn.Recv(context.Background())
+			errCh <- err
+		}, conf)
+		assert.NoError(t, err)
+
+		raw, err := net.Dial("tcp", server.Addr())
+		assert.NoError(t, err)
+		codec := network.NewCodec(MaxIncomingPacket, false, 0)
+		assert.NoError(t, clientKeyExchange(raw, codec, &network.EncryptionOptions{Suite: network.CipherAES256GCM}))
+
+		//链路上伪造的明文关闭通知或序号确认不会被处理, 服务端以 ErrPlaintextFrame 断开连接
+		plain := network.NewCodec(MaxIncomingPacket, false, 0)
+		pack := plain.EncodeBody(network.EncodeSeqAck(math.MaxUint64), h)
+		_, err = raw.Write(pack.Data())
+		assert.NoError(t, err)
+		packet2.Return(pack)
+
+		select {
+		case err = <-errCh:
+			assert.ErrorIs(t, err, network.ErrPlaintextFrame)
+		case <-time.After(time.Second * 5):
+			t.Fatalf("plaintext control frame %d not rejected", h)
+		}
+		_ = raw.Close()
+		_ = server.Stop()
+	}
+}
@@ -16,6 +16,21 @@ var (
 	ErrCanceled     = errors.New("context canceled")
 	ErrDisconnected = errors.New("disconnected")
 	ErrMaxOfRetry   = errors.New(`error_max_of_retry`)
+
+	ErrIdleTimeout      = errors.New("idle timeout")
+	ErrSendQueueFull    = errors.New("send queue full")
+	ErrDatagramTooLarge = errors.New("datagram too large")
+	ErrServerShutdown   = errors.New("server shutdown") //收到服务端的关闭通知
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+	ErrRateLimited      = errors.New("inbound rate limited")
+	ErrSlowConsumer     = errors.New("slow consumer") //发送队列积压超过高水位, 连接被断开
+	ErrMalformedFrame   = errors.New("malformed frame")
+	ErrPoolClosed       = errors.New("pool closed")
+	ErrNoAvailableConn  = errors.New("no available conn") //连接池中没有可用的连接
+
+	ErrMemAddrInUse      = errors.New("mem: address already in use")
+	ErrMemConnRefused    = errors.New("mem: connection refused")
+	ErrMemListenerClosed = errors.New("mem: listener closed")
 )
 
 func IsClosedConnError(err error) bool {
@@ -0,0 +1,125 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: graceful_test
+   @2026 10月 周日 17:10
+*/
+
+func Test_GracefulStop(t *testing.T) {
+	const count = 1000
+	ready := make(chan struct{})
+	server, err := Serve("tcp", "127.0.0.1:0", func(conn IConn) {
+		if _, err := conn.Recv(context.Background()); err != nil {
+			return
+		}
+		for i := 0; i < count; i++ {
+			_ = conn.Send([]byte(fmt.Sprintf("msg-%d", i)))
+		}
+		close(ready)
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				return
+			}
+		}
+	})
+	assert.NoError(t, err)
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+	assert.NoError(t, conn.Send([]byte("start")))
+	<-ready
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		defer cancel()
+		stopped <- server.GracefulStop(ctx)
+	}()
+
+	//关闭前缓存的数据全部送达, 随后收到关闭通知
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for i := 0; i < count; i++ {
+		in, err := conn.Recv(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("msg-%d", i), string(in))
+	}
+	_, err = conn.Recv(ctx)
+	assert.ErrorIs(t, err, ErrServerShutdown)
+	assert.NoError(t, <-stopped)
+
+	_, err = net.DialTimeout("tcp", server.Addr(), time.Second)
+	assert.Error(t, err)
+}
+
+func Test_GracefulStopTimeout(t *testing.T) {
+	exited := make(chan struct{})
+	server, err := Serve("tcp", "127.0.0.1:0", func(conn IConn) {
+		defer close(exited)
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				return
+			}
+		}
+	})
+	assert.NoError(t, err)
+
+	//不处理关闭通知的对端, 超时后被强制断开
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	time.Sleep(time.Millisecond * 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+	assert.ErrorIs(t, server.GracefulStop(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second * 5):
+		t.Fatal("handle not exited")
+	}
+}
+
+func Test_StopWithoutCloseNotice(t *testing.T) {
+	accepted := make(chan struct{})
+	server, err := Serve("tcp", "127.0.0.1:0", func(conn IConn) {
+		close(accepted)
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				return
+			}
+		}
+	})
+	assert.NoError(t, err)
+
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	<-accepted
+
+	//Stop 立即停止, 不像 GracefulStop 那样向存活连接发送关闭通知
+	assert.NoError(t, server.Stop())
+	_ = raw.SetReadDeadline(time.Now().Add(time.Millisecond * 300))
+	_, err = raw.Read(make([]byte, 1))
+	var ne net.Error
+	assert.ErrorAs(t, err, &ne)
+	assert.True(t, ne.Timeout())
+}
@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: handshake
+   @2026 10月 周日 22:55
+*/
+
+// acceptHandshake 读取客户端的握手帧并交给 op.Validate 校验, 回复接受或者拒绝应答.
+// 超过 op.Timeout 未完成时关闭连接, 返回 ErrHandshakeTimeout
+func acceptHandshake(ctx context.Context, conn net.Conn, codec *mnetwork.Codec, head, body []byte,
+	op *mnetwork.HandshakeOptions) (*mnetwork.HandshakeRequest, error) {
+	timeout := op.Timeout
+	if timeout <= 0 {
+		timeout = HandshakeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	//读取握手帧与 Validate 都受 timeout 限制, 超时或者服务端停止时关闭连接
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+
+	var reject error
+	req, err := readHandshake(conn, codec, head, body)
+	if err == nil {
+		req.RemoteAddr = conn.RemoteAddr().String()
+		if op.Validate != nil {
+			reject = op.Validate(ctx, req)
+		}
+	}
+	if !stop() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, mnetwork.ErrHandshakeTimeout
+		}
+		return nil, ErrCanceled
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = writeFrame(conn, codec, mnetwork.EncodeHandshakeAck(reject), mnetwork.TypeMessageHandshakeAck); err != nil {
+		return nil, err
+	}
+	if reject != nil {
+		return nil, mnetwork.HandshakeRejected(reject.Error())
+	}
+	return req, nil
+}
+
+func readHandshake(conn net.Conn, codec *mnetwork.Codec, head, body []byte) (*mnetwork.HandshakeRequest, error) {
+	data, h, err := codec.BlockDecodeBody(conn, head, body)
+	if err != nil {
+		return nil, err
+	}
+	if h != mnetwork.TypeMessageHandshake {
+		return nil, mnetwork.ErrHandshakeFailed
+	}
+	return mnetwork.DecodeHandshakeRequest(data)
+}
+
+// sendHandshake 发送握手帧并等待服务端应答, 服务端拒绝时返回包含原因的 ErrHandshakeRejected
+func sendHandshake(conn net.Conn, codec *mnetwork.Codec, req *mnetwork.HandshakeRequest) error {
+	if err := writeFrame(conn, codec, req.Encode(), mnetwork.TypeMessageHandshake); err != nil {
+		return err
+	}
+
+	data, buf, h, err := codec.BlockDecodeBuffer(conn, make([]byte, HeadLen))
+	if err != nil {
+		return err
+	}
+	defer buf.Release()
+	if h != mnetwork.TypeMessageHandshakeAck {
+		return mnetwork.ErrHandshakeFailed
+	}
+	return mnetwork.DecodeHandshakeAck(data)
+}
+
+// writeFrame 绕过发送协程直接向 conn 写出一帧类型为 h 的消息, 用于握手、会话恢复的请求与应答以及重放,
+// 调用方需要保证此时没有发送协程在写 conn. 使用连接的 codec 编码, 开启加密时同样被加密, 写超时为 HandshakeTimeout
+func writeFrame(conn net.Conn, codec *mnetwork.Codec, data []byte, h int8) error {
+	pack, err := codec.Encode(data, h)
+	if err != nil {
+		return err
+	}
+	defer packet2.Return(pack)
+	if err = conn.SetWriteDeadline(time.Now().Add(HandshakeTimeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(pack.Data())
+	return err
+}
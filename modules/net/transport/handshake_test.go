@@ -0,0 +1,187 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: handshake_test
+   @2026 10月 周日 23:20
+*/
+
+var errInvalidToken = errors.New("invalid token")
+
+// serveHandshake 启动开启握手的回声服务端, handled 统计进入 _handle 的连接数
+func serveHandshake(t *testing.T, conf *Config, handled *atomic.Int32) IServer {
+	if conf.Handshake == nil {
+		conf.Handshake = &network.HandshakeOptions{
+			Validate: func(ctx context.Context, req *network.HandshakeRequest) error {
+				if req.Version != 1 || !bytes.Equal(req.Token, []byte("secret")) {
+					return errInvalidToken
+				}
+				return nil
+			},
+		}
+	}
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		handled.Add(1)
+		req := conn.(IHandshake).Handshake()
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(append([]byte(req.Metadata["platform"]+":"), in...))
+		}
+	}, conf)
+	assert.NoError(t, err)
+	return server
+}
+
+func Test_Handshake(t *testing.T) {
+	var handled atomic.Int32
+	server := serveHandshake(t, DefaultServerConfig(), &handled)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithHandshake(&network.HandshakeRequest{
+			Version:  1,
+			Token:    []byte("secret"),
+			Metadata: map[string]string{"platform": "ios"},
+		}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("hello")))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "ios:hello", string(in))
+	assert.Equal(t, int32(1), handled.Load())
+}
+
+func Test_HandshakeEncrypted(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	conf := DefaultServerConfig()
+	conf.Encryption = &network.EncryptionOptions{PrivateKey: priv}
+	var handled atomic.Int32
+	server := serveHandshake(t, conf, &handled)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithEncryption(&network.EncryptionOptions{
+			Suite:         network.CipherChaCha20Poly1305,
+			PeerPublicKey: priv.PublicKey().Bytes(),
+		}),
+		WithHandshake(&network.HandshakeRequest{Version: 1, Token: []byte("secret")}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("hello")))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ":hello", string(in))
+}
+
+func Test_HandshakeRejected(t *testing.T) {
+	var handled atomic.Int32
+	server := serveHandshake(t, DefaultServerConfig(), &handled)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithHandshake(&network.HandshakeRequest{Version: 1, Token: []byte("wrong")}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//被拒绝后不再重试
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*2)
+	defer cancel()
+	_, err := conn.Recv(ctx)
+	assert.ErrorIs(t, err, network.ErrHandshakeRejected)
+	assert.Contains(t, err.Error(), errInvalidToken.Error())
+	assert.Equal(t, int32(0), handled.Load())
+}
+
+func Test_HandshakeMissing(t *testing.T) {
+	var handled atomic.Int32
+	server := serveHandshake(t, DefaultServerConfig(), &handled)
+	defer server.Stop()
+
+	//未握手的客户端直接发送数据帧
+	raw, err := dialMem(server.Addr(), nil)
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	pack := network.NewCodec(MaxIncomingPacket, false, 0).EncodeBody([]byte("hello"), network.TypeMessageRaw)
+	_, err = raw.Write(pack.Data())
+	assert.NoError(t, err)
+
+	_ = raw.SetReadDeadline(time.Now().Add(time.Second * 5))
+	_, err = raw.Read(make([]byte, 16))
+	assert.True(t, IsClosedConnError(err) || errors.Is(err, io.EOF), err)
+	assert.Equal(t, int32(0), handled.Load())
+}
+
+func Test_HandshakeTimeout(t *testing.T) {
+	validating := make(chan struct{}, 1)
+	conf := DefaultServerConfig()
+	conf.Handshake = &network.HandshakeOptions{
+		Timeout: time.Millisecond * 100,
+		Validate: func(ctx context.Context, req *network.HandshakeRequest) error {
+			//握手超时后客户端会重连, Validate 被多次调用
+			select {
+			case validating <- struct{}{}:
+			default:
+			}
+			<-ctx.Done()
+			return nil
+		},
+	}
+	var handled atomic.Int32
+	server := serveHandshake(t, conf, &handled)
+	defer server.Stop()
+
+	//不发送握手帧的连接
+	idle, err := dialMem(server.Addr(), nil)
+	assert.NoError(t, err)
+	defer func() {
+		_ = idle.Close()
+	}()
+	_ = idle.SetReadDeadline(time.Now().Add(time.Second * 5))
+	_, err = idle.Read(make([]byte, 16))
+	assert.ErrorIs(t, err, io.EOF)
+
+	//Validate 超时
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithHandshake(&network.HandshakeRequest{Version: 1}))
+	defer func() {
+		_ = conn.Close()
+	}()
+	select {
+	case <-validating:
+	case <-time.After(time.Second * 5):
+		t.Fatal("validate not called")
+	}
+	time.Sleep(time.Millisecond * 200)
+	assert.Equal(t, int32(0), handled.Load())
+}
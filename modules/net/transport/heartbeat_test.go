@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: heartbeat_test
+   @2026 10月 周日 18:20
+*/
+
+func Test_HeartbeatTimeout(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.HeartbeatTimeout = time.Millisecond * 300
+	closed := make(chan error, 1)
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	//不回复探测的对端
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+
+	codec := network.NewCodec(MaxIncomingPacket, false, time.Second*5)
+	_, head, err := codec.BlockDecodeBody(raw, make([]byte, HeadLen), make([]byte, MaxIncomingPacket))
+	assert.NoError(t, err)
+	assert.Equal(t, int8(network.TypeMessageProbe), head)
+
+	select {
+	case err = <-closed:
+		assert.ErrorIs(t, err, ErrHeartbeatTimeout)
+	case <-time.After(time.Second * 5):
+		t.Fatal("heartbeat timeout not detected")
+	}
+}
+
+func Test_HeartbeatProbe(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.HeartbeatTimeout = time.Millisecond * 300
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	//客户端回复探测, 空闲超过 HeartbeatTimeout 后连接仍然可用
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello")
+	time.Sleep(time.Second)
+	assertEcho(t, conn, "still alive")
+}
@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"net"
+
+	"github.com/xtaci/kcp-go/v5"
+)
+
+/*
+   @Author: orbit-w
+   @File: kcp_conn
+   @2026 10月 周日 10:12
+*/
+
+// dialKcp 建立 KCP 会话, 返回的 *kcp.UDPSession 实现了 net.Conn
+func dialKcp(remoteAddr string) (net.Conn, error) {
+	sess, err := kcp.DialWithOptions(remoteAddr, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	_ = sess.SetReadBuffer(KcpSockBuf)
+	_ = sess.SetWriteBuffer(KcpSockBuf)
+	tuneKcpSession(sess)
+	return sess, nil
+}
+
+// tuneKcpSession 以低延迟模式配置 KCP 会话
+// 开启流模式, 使 network.Codec 的分帧不受 KCP 单条消息分片上限的约束
+func tuneKcpSession(sess *kcp.UDPSession) {
+	sess.SetStreamMode(true)
+	sess.SetWriteDelay(false)
+	sess.SetNoDelay(KcpNoDelay, KcpInterval, KcpResend, KcpNoCongestion)
+	sess.SetWindowSize(KcpSendWindow, KcpRecvWindow)
+	sess.SetMtu(KcpMtu)
+	sess.SetACKNoDelay(true)
+}
@@ -1,7 +1,89 @@
 package transport
 
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	gnetwork "github.com/orbit-w/meteor/modules/net/network"
+	"github.com/xtaci/kcp-go/v5"
+)
+
 /*
    @Author: orbit-w
    @File: kcp_server
    @2024 4月 周二 16:32
 */
+
+func init() {
+	RegisterFactory(gnetwork.KCP, func() ITransportServer {
+		return &KcpServer{}
+	})
+}
+
+// KcpServer 基于 KCP(ARQ over UDP) 的传输层服务端
+// 每个 KCP 会话以流模式工作，复用 TcpServerConn 与 network.Codec 的分帧逻辑，
+// 因此业务层只需要将协议字符串从 "tcp" 切换为 "kcp" 即可
+type KcpServer struct {
+	server *gnetwork.Server
+}
+
+func (k *KcpServer) Serve(host string, _handle func(conn IConn), op *gnetwork.AcceptorOptions) error {
+	listener, err := kcp.ListenWithOptions(host, nil, 0, 0)
+	if err != nil {
+		return err
+	}
+	_ = listener.SetReadBuffer(KcpSockBuf)
+	_ = listener.SetWriteBuffer(KcpSockBuf)
+
+	var l net.Listener = &kcpListener{Listener: listener}
+	if op.TLSConfig != nil {
+		l = tls.NewListener(l, op.TLSConfig)
+	}
+
+	server := new(gnetwork.Server)
+	server.Serve(gnetwork.KCP, l, func(ctx context.Context, generic net.Conn, head, body []byte,
+		options *gnetwork.AcceptorOptions) {
+		serveConn(ctx, generic, head, body, options, _handle)
+	}, op)
+	k.server = server
+	return nil
+}
+
+func (k *KcpServer) Addr() string {
+	if k.server != nil {
+		return k.server.Addr()
+	}
+	return ""
+}
+
+// GracefulStop 参考 TcpServer.GracefulStop
+func (k *KcpServer) GracefulStop(ctx context.Context) error {
+	if k.server != nil {
+		return k.server.GracefulStop(ctx)
+	}
+	return nil
+}
+
+// Stop stops the server
+// 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
+func (k *KcpServer) Stop() error {
+	if k.server != nil {
+		_ = k.server.Stop()
+	}
+	return nil
+}
+
+// kcpListener 在 Accept 时为每个 KCP 会话设置传输参数
+type kcpListener struct {
+	*kcp.Listener
+}
+
+func (l *kcpListener) Accept() (net.Conn, error) {
+	sess, err := l.AcceptKCP()
+	if err != nil {
+		return nil, err
+	}
+	tuneKcpSession(sess)
+	return sess, nil
+}
@@ -0,0 +1,61 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: kcp_test
+   @2026 10月 周日 10:40
+*/
+
+func Test_KcpEcho(t *testing.T) {
+	server, err := Serve("kcp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				break
+			}
+			_ = conn.Send(in)
+		}
+	})
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.KCP))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for _, msg := range []string{"hello", "kcp", "world"} {
+		assert.NoError(t, conn.Send([]byte(msg)))
+		in, err := conn.Recv(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, string(in))
+	}
+}
+
+func Test_KcpEchoBig(t *testing.T) {
+	server, wait, err := ServePlannedTraffic("kcp", "127.0.0.1:0", 1024*128*10)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.KCP))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	buf := make([]byte, 1024*128)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, conn.Send(buf))
+	}
+	wait()
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: limit_test
+   @2026 10月 周日 19:12
+*/
+
+func Test_LimitConnsPerIP(t *testing.T) {
+	limited := make(chan network.LimitReason, 1)
+	conf := DefaultServerConfig()
+	conf.Limit = network.LimitOptions{
+		MaxConnsPerIP: 1,
+		OnLimit: func(reason network.LimitReason, _ string) {
+			limited <- reason
+		},
+	}
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello")
+
+	//同一 IP 的第二条连接被直接关闭
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	_ = raw.SetReadDeadline(time.Now().Add(time.Second * 5))
+	_, err = raw.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.Equal(t, network.LimitConnsPerIP, <-limited)
+}
+
+func Test_LimitPacketRate(t *testing.T) {
+	limited := make(chan network.LimitReason, 1)
+	closed := make(chan error, 1)
+	conf := DefaultServerConfig()
+	conf.Limit = network.LimitOptions{
+		PacketRate:  10,
+		PacketBurst: 10,
+		OnLimit: func(reason network.LimitReason, _ string) {
+			limited <- reason
+		},
+	}
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	//每个数据帧单独发送, 超过突发上限后连接被关闭
+	raw, err := net.Dial("tcp", server.Addr())
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	codec := network.NewCodec(MaxIncomingPacket, false, 0)
+	ping := codec.EncodeBody(nil, network.TypeMessageHeartbeat)
+	for i := 0; i < 100; i++ {
+		if _, err = raw.Write(ping.Data()); err != nil {
+			break
+		}
+	}
+
+	select {
+	case err = <-closed:
+		assert.ErrorIs(t, err, ErrRateLimited)
+	case <-time.After(time.Second * 5):
+		t.Fatal("packet flood not limited")
+	}
+	assert.Equal(t, network.LimitPacketRate, <-limited)
+}
@@ -0,0 +1,286 @@
+package transport
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+   @Author: orbit-w
+   @File: mem_conn
+   @2026 10月 周日 19:20
+*/
+
+// MemReorderDelay 乱序的写入在正常到达时间之后额外延迟的时长, 其后的写入先于它到达
+const MemReorderDelay = time.Millisecond * 5
+
+// MemLinkOptions 内存连接的链路模拟参数, 作用于连接的两个方向.
+// 丢包与乱序以一次 Write 为单位, 发送方每次写出完整的帧, 因此丢弃或者乱序的是整帧;
+// 加密与 TLS 依赖有序无损的字节流, 开启丢包或乱序时不能使用
+type MemLinkOptions struct {
+	Latency     time.Duration //单向时延
+	Jitter      time.Duration //时延在 [Latency, Latency+Jitter) 之间随机, 未乱序的写入仍按写入顺序到达
+	LossRate    float64       //丢弃一次写入的概率
+	ReorderRate float64       //一次写入额外延迟 MemReorderDelay 的概率
+	Seed        int64         //随机数种子, 相同的种子与写入序列得到相同的丢包与乱序结果
+	Clock       Clock         //模拟时延使用的时钟, 为空时使用真实时钟; 读超时等其他定时器不受影响
+}
+
+// memAddr 内存连接的地址
+type memAddr string
+
+func (a memAddr) Network() string {
+	return "mem"
+}
+
+func (a memAddr) String() string {
+	return string(a)
+}
+
+// memChunk 一次写入的数据以及到达时间
+type memChunk struct {
+	at   time.Time
+	data []byte
+}
+
+// memPipe 内存连接的一个方向: 写入的数据按链路参数延迟、丢弃或乱序后进入接收缓冲区, 写入不会阻塞
+type memPipe struct {
+	mu        sync.Mutex
+	link      MemLinkOptions
+	clock     Clock
+	rand      *rand.Rand
+	buf       []byte
+	inTransit []memChunk //按到达时间排序
+	last      time.Time  //最近一次未乱序写入的到达时间
+	deadline  time.Time  //读超时, 使用真实时间
+	rClosed   bool       //读端关闭
+	wClosed   bool       //写端关闭, 在途数据到达后读端返回 io.EOF
+	notify    chan struct{}
+}
+
+func newMemPipe(link *MemLinkOptions, seed int64) *memPipe {
+	p := &memPipe{
+		clock:  realClock{},
+		notify: make(chan struct{}, 1),
+	}
+	if link != nil {
+		p.link = *link
+		if link.Clock != nil {
+			p.clock = link.Clock
+		}
+	}
+	p.rand = rand.New(rand.NewSource(seed))
+	return p
+}
+
+func (p *memPipe) write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case p.wClosed:
+		return 0, net.ErrClosed
+	case p.rClosed:
+		return 0, io.ErrClosedPipe
+	}
+
+	link := &p.link
+	if link.LossRate > 0 && p.rand.Float64() < link.LossRate {
+		return len(b), nil
+	}
+
+	now := p.clock.Now()
+	at := now.Add(link.Latency)
+	if link.Jitter > 0 {
+		at = at.Add(time.Duration(p.rand.Int63n(int64(link.Jitter))))
+	}
+	if link.ReorderRate > 0 && p.rand.Float64() < link.ReorderRate {
+		at = at.Add(MemReorderDelay)
+	} else {
+		if at.Before(p.last) {
+			at = p.last
+		}
+		p.last = at
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+	if !at.After(now) && len(p.inTransit) == 0 {
+		p.buf = append(p.buf, data...)
+		p.signal()
+		return len(b), nil
+	}
+
+	//插入到相同到达时间的写入之后
+	i := len(p.inTransit)
+	for i > 0 && p.inTransit[i-1].at.After(at) {
+		i--
+	}
+	p.inTransit = append(p.inTransit, memChunk{})
+	copy(p.inTransit[i+1:], p.inTransit[i:])
+	p.inTransit[i] = memChunk{at: at, data: data}
+	p.clock.AfterFunc(at.Sub(now), p.deliver)
+	return len(b), nil
+}
+
+// deliver 将已到达的在途数据移入接收缓冲区
+func (p *memPipe) deliver() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clock.Now()
+	n := 0
+	for n < len(p.inTransit) && !p.inTransit[n].at.After(now) {
+		if !p.rClosed {
+			p.buf = append(p.buf, p.inTransit[n].data...)
+		}
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	clear(p.inTransit[:n])
+	p.inTransit = p.inTransit[n:]
+	p.signal()
+}
+
+func (p *memPipe) read(b []byte) (int, error) {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		p.mu.Lock()
+		switch {
+		case p.rClosed:
+			p.mu.Unlock()
+			return 0, net.ErrClosed
+		case len(p.buf) > 0:
+			n := copy(b, p.buf)
+			p.buf = p.buf[n:]
+			if len(p.buf) == 0 {
+				p.buf = nil
+			}
+			p.mu.Unlock()
+			return n, nil
+		case p.wClosed && len(p.inTransit) == 0:
+			p.mu.Unlock()
+			return 0, io.EOF
+		}
+
+		var timeout <-chan time.Time
+		if deadline := p.deadline; !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				p.mu.Unlock()
+				return 0, os.ErrDeadlineExceeded
+			}
+			if timer == nil {
+				timer = time.NewTimer(d)
+			} else {
+				timer.Reset(d)
+			}
+			timeout = timer.C
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-p.notify:
+		case <-timeout:
+			timer = nil
+		}
+	}
+}
+
+func (p *memPipe) setDeadline(t time.Time) {
+	p.mu.Lock()
+	p.deadline = t
+	p.signal()
+	p.mu.Unlock()
+}
+
+func (p *memPipe) closeRead() {
+	p.mu.Lock()
+	p.rClosed = true
+	p.buf = nil
+	p.signal()
+	p.mu.Unlock()
+}
+
+func (p *memPipe) closeWrite() {
+	p.mu.Lock()
+	p.wClosed = true
+	p.signal()
+	p.mu.Unlock()
+}
+
+// signal 唤醒阻塞的读, 调用方需持有 mu
+func (p *memPipe) signal() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// memConn 内存连接的一端, 实现 net.Conn
+type memConn struct {
+	local  memAddr
+	remote memAddr
+	r      *memPipe
+	w      *memPipe
+	once   sync.Once
+}
+
+// newMemConnPair 创建一对相连的内存连接, 两个方向使用相同的链路参数
+func newMemConnPair(client, server memAddr, link *MemLinkOptions) (*memConn, *memConn) {
+	var seed int64
+	if link != nil {
+		seed = link.Seed
+	}
+	up, down := newMemPipe(link, seed), newMemPipe(link, seed+1)
+	return &memConn{local: client, remote: server, r: down, w: up},
+		&memConn{local: server, remote: client, r: up, w: down}
+}
+
+func (c *memConn) Read(b []byte) (int, error) {
+	return c.r.read(b)
+}
+
+func (c *memConn) Write(b []byte) (int, error) {
+	return c.w.write(b)
+}
+
+func (c *memConn) Close() error {
+	c.once.Do(func() {
+		c.r.closeRead()
+		c.w.closeWrite()
+	})
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *memConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *memConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	c.r.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline 写入不会阻塞, 忽略写超时
+func (c *memConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
@@ -0,0 +1,159 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	gnetwork "github.com/orbit-w/meteor/modules/net/network"
+)
+
+/*
+   @Author: orbit-w
+   @File: mem_server
+   @2026 10月 周日 19:40
+*/
+
+func init() {
+	RegisterFactory(gnetwork.MEM, func() ITransportServer {
+		return &MemServer{}
+	})
+}
+
+// MemServer 进程内的内存传输层服务端, 用于测试: 不占用端口, 连接建立与读写都不经过系统调用.
+// 连接复用 TcpServerConn 与 network.Codec 的分帧逻辑, 客户端通过 WithProtocol(network.MEM) 拨号,
+// 通过 WithMemLink 模拟时延、丢包与乱序
+type MemServer struct {
+	server *gnetwork.Server
+}
+
+// Serve host 为监听的名字, 为空或者以 ":0" 结尾时自动分配
+func (m *MemServer) Serve(host string, _handle func(conn IConn), op *gnetwork.AcceptorOptions) error {
+	listener, err := listenMem(host)
+	if err != nil {
+		return err
+	}
+
+	var l net.Listener = listener
+	if op.TLSConfig != nil {
+		l = tls.NewListener(l, op.TLSConfig)
+	}
+
+	server := new(gnetwork.Server)
+	server.Serve(gnetwork.MEM, l, func(ctx context.Context, generic net.Conn, head, body []byte,
+		options *gnetwork.AcceptorOptions) {
+		serveConn(ctx, generic, head, body, options, _handle)
+	}, op)
+	m.server = server
+	return nil
+}
+
+func (m *MemServer) Addr() string {
+	if m.server != nil {
+		return m.server.Addr()
+	}
+	return ""
+}
+
+// GracefulStop 参考 TcpServer.GracefulStop
+func (m *MemServer) GracefulStop(ctx context.Context) error {
+	if m.server != nil {
+		return m.server.GracefulStop(ctx)
+	}
+	return nil
+}
+
+// Stop stops the server
+// 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
+func (m *MemServer) Stop() error {
+	if m.server != nil {
+		_ = m.server.Stop()
+	}
+	return nil
+}
+
+// memRegistry 进程内的内存监听地址表
+var memRegistry = struct {
+	sync.Mutex
+	seq       uint64
+	listeners map[string]*memListener
+}{listeners: make(map[string]*memListener)}
+
+// memListener 内存连接的监听器, 拨号时创建一对内存连接并将服务端一端交给 Accept
+type memListener struct {
+	addr   memAddr
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func listenMem(host string) (*memListener, error) {
+	memRegistry.Lock()
+	defer memRegistry.Unlock()
+	switch {
+	case host == "":
+		memRegistry.seq++
+		host = "mem:" + strconv.FormatUint(memRegistry.seq, 10)
+	case strings.HasSuffix(host, ":0"):
+		memRegistry.seq++
+		host = strings.TrimSuffix(host, "0") + strconv.FormatUint(memRegistry.seq, 10)
+	}
+	if memRegistry.listeners[host] != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMemAddrInUse, host)
+	}
+
+	l := &memListener{
+		addr:   memAddr(host),
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	memRegistry.listeners[host] = l
+	return l, nil
+}
+
+// dialMem 连接 host 上的内存监听器, 阻塞直到服务端 Accept
+func dialMem(host string, link *MemLinkOptions) (net.Conn, error) {
+	memRegistry.Lock()
+	l := memRegistry.listeners[host]
+	memRegistry.seq++
+	local := memAddr("mem-client:" + strconv.FormatUint(memRegistry.seq, 10))
+	memRegistry.Unlock()
+	if l == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMemConnRefused, host)
+	}
+
+	client, server := newMemConnPair(local, l.addr, link)
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, fmt.Errorf("%w: %s", ErrMemConnRefused, host)
+	}
+}
+
+func (l *memListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, ErrMemListenerClosed
+	}
+}
+
+func (l *memListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+		memRegistry.Lock()
+		delete(memRegistry.listeners, string(l.addr))
+		memRegistry.Unlock()
+	})
+	return nil
+}
+
+func (l *memListener) Addr() net.Addr {
+	return l.addr
+}
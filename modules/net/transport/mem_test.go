@@ -0,0 +1,268 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: mem_test
+   @2026 10月 周日 20:10
+*/
+
+func serveMemEcho(t *testing.T) IServer {
+	server, err := Serve("mem", "", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	})
+	assert.NoError(t, err)
+	return server
+}
+
+func Test_MemEcho(t *testing.T) {
+	server := serveMemEcho(t)
+	defer server.Stop()
+	assert.True(t, strings.HasPrefix(server.Addr(), "mem:"))
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for i := 0; i < 100; i++ {
+		msg := fmt.Sprintf("message-%d", i)
+		assert.NoError(t, conn.Send([]byte(msg)))
+		in, err := conn.Recv(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, msg, string(in))
+	}
+}
+
+func Test_MemManyClients(t *testing.T) {
+	server := serveMemEcho(t)
+	defer server.Stop()
+
+	const num = 2000
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*20)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < num; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn := DialWithOps(ctx, server.Addr(), WithProtocol(network.MEM))
+			defer func() {
+				_ = conn.Close()
+			}()
+			msg := fmt.Sprintf("client-%d", i)
+			assert.NoError(t, conn.Send([]byte(msg)))
+			in, err := conn.Recv(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, msg, string(in))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func Test_MemVirtualLatency(t *testing.T) {
+	server := serveMemEcho(t)
+	defer server.Stop()
+
+	clock := NewVirtualClock(time.Now())
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithMemLink(&MemLinkOptions{Latency: time.Millisecond * 100, Clock: clock}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	assert.NoError(t, conn.Send([]byte("hello")))
+	//请求与回复各经过一次单向时延, 时间不推进时消息停留在链路上
+	for i := 0; i < 2; i++ {
+		assert.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second*5, time.Millisecond)
+		clock.Advance(time.Millisecond * 99)
+		assert.Equal(t, 1, clock.Pending())
+		clock.Advance(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(in))
+}
+
+func Test_MemLoss(t *testing.T) {
+	received := make(chan []byte, 1)
+	server, err := Serve("mem", "", func(conn IConn) {
+		in, err := conn.Recv(context.Background())
+		if err == nil {
+			received <- in
+		}
+	})
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM),
+		WithMemLink(&MemLinkOptions{LossRate: 1}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	assert.NoError(t, conn.Send([]byte("lost")))
+	select {
+	case <-received:
+		t.Fatal("message should be lost")
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+// pipeWrites 通过链路写入 num 次, 推进时钟后读出全部到达的数据
+func pipeWrites(link *MemLinkOptions, clock *VirtualClock, num int) string {
+	client, server := newMemConnPair("client", "server", link)
+	for i := 0; i < num; i++ {
+		_, _ = client.Write([]byte{byte('a' + i)})
+	}
+	clock.Advance(link.Latency + link.Jitter + MemReorderDelay)
+	_ = client.Close()
+
+	data, _ := io.ReadAll(server)
+	return string(data)
+}
+
+func Test_MemLinkDeterministic(t *testing.T) {
+	const num = 20
+	link := func(clock *VirtualClock) *MemLinkOptions {
+		return &MemLinkOptions{
+			Latency:     time.Millisecond * 10,
+			Jitter:      time.Millisecond * 10,
+			LossRate:    0.2,
+			ReorderRate: 0.2,
+			Seed:        7,
+			Clock:       clock,
+		}
+	}
+
+	clock := NewVirtualClock(time.Now())
+	first := pipeWrites(link(clock), clock, num)
+	assert.Less(t, len(first), num)
+	assert.NotEqual(t, 0, len(first))
+	sorted := []byte(first)
+	slices.Sort(sorted)
+	assert.NotEqual(t, string(sorted), first)
+
+	//相同的种子得到相同的结果
+	clock = NewVirtualClock(time.Now())
+	assert.Equal(t, first, pipeWrites(link(clock), clock, num))
+}
+
+func Test_MemLinkJitterKeepsOrder(t *testing.T) {
+	clock := NewVirtualClock(time.Now())
+	link := &MemLinkOptions{Latency: time.Millisecond, Jitter: time.Millisecond * 50, Seed: 1, Clock: clock}
+	assert.Equal(t, "abcdefghijklmnopqrst", pipeWrites(link, clock, 20))
+}
+
+func Test_MemConn(t *testing.T) {
+	client, server := newMemConnPair("client", "server", nil)
+	_ = server.SetReadDeadline(time.Now().Add(time.Millisecond * 20))
+	buf := make([]byte, 8)
+	_, err := server.Read(buf)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	_ = server.SetReadDeadline(time.Time{})
+	_, err = client.Write([]byte("ping"))
+	assert.NoError(t, err)
+	n, err := server.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+
+	_ = client.Close()
+	_, err = server.Read(buf)
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = server.Write([]byte("pong"))
+	assert.True(t, IsClosedConnError(err))
+	_, err = client.Read(buf)
+	assert.True(t, IsClosedConnError(err))
+}
+
+func Test_MemListen(t *testing.T) {
+	server, err := Serve("mem", "room:0", func(conn IConn) {})
+	assert.NoError(t, err)
+	defer server.Stop()
+	assert.True(t, strings.HasPrefix(server.Addr(), "room:"))
+	assert.NotEqual(t, "room:0", server.Addr())
+
+	_, err = Serve("mem", server.Addr(), func(conn IConn) {})
+	assert.ErrorIs(t, err, ErrMemAddrInUse)
+
+	_, err = dialMem("room:unknown", nil)
+	assert.ErrorIs(t, err, ErrMemConnRefused)
+
+	addr := server.Addr()
+	_ = server.Stop()
+	_, err = dialMem(addr, nil)
+	assert.ErrorIs(t, err, ErrMemConnRefused)
+}
+
+func Test_VirtualClock(t *testing.T) {
+	start := time.Now()
+	clock := NewVirtualClock(start)
+	var fired []string
+	clock.AfterFunc(time.Second*2, func() { fired = append(fired, "b") })
+	clock.AfterFunc(time.Second, func() {
+		fired = append(fired, "a")
+		//回调中注册的回调在同一次 Advance 中到期
+		clock.AfterFunc(time.Millisecond*500, func() { fired = append(fired, "a2") })
+	})
+	clock.AfterFunc(time.Second*2, func() { fired = append(fired, "c") })
+
+	clock.Advance(time.Millisecond * 1999)
+	assert.Equal(t, []string{"a", "a2"}, fired)
+	assert.Equal(t, start.Add(time.Millisecond*1999), clock.Now())
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, []string{"a", "a2", "b", "c"}, fired)
+	assert.Equal(t, 0, clock.Pending())
+}
+
+// 大量回调按 (到期时间, 注册顺序) 依次执行
+func Test_VirtualClockMany(t *testing.T) {
+	const count = 100000
+	clock := NewVirtualClock(time.Now())
+	var last time.Time
+	var lastSeq, fired int
+	for i := 0; i < count; i++ {
+		seq := i
+		d := time.Duration((i*7919)%1000) * time.Millisecond
+		clock.AfterFunc(d, func() {
+			now := clock.Now()
+			assert.False(t, now.Before(last))
+			if now.Equal(last) {
+				assert.Greater(t, seq, lastSeq)
+			}
+			last, lastSeq = now, seq
+			fired++
+		})
+	}
+	clock.Advance(time.Second)
+	assert.Equal(t, count, fired)
+	assert.Equal(t, 0, clock.Pending())
+}
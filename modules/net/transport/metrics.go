@@ -0,0 +1,26 @@
+package transport
+
+import (
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+)
+
+/*
+   @Author: orbit-w
+   @File: metrics
+   @2026 10月 周日 23:55
+*/
+
+// ClientMetricsName 客户端指标导出时的 server 标签
+const ClientMetricsName = "client"
+
+// clientMetrics 进程内所有客户端连接(TcpClient, UdpClient)共享的指标
+var clientMetrics = mnetwork.NewMetrics()
+
+func init() {
+	mnetwork.RegisterMetrics(ClientMetricsName, clientMetrics)
+}
+
+// ClientMetrics 返回进程内所有客户端连接共享的指标
+func ClientMetrics() *mnetwork.Metrics {
+	return clientMetrics
+}
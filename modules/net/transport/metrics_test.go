@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: metrics_test
+   @2026 10月 周一 00:20
+*/
+
+func Test_Metrics(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.MetricsName = "metrics-test"
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	for i := 0; i < 10; i++ {
+		assertEcho(t, conn, "hello, metrics")
+	}
+	assert.Equal(t, int64(1), server.Metrics().Conns())
+
+	out := scrape(t)
+	for _, line := range []string{
+		`meteor_transport_connections{server="metrics-test"} 1`,
+		`meteor_transport_packets_received_total{server="metrics-test"} 10`,
+		`meteor_transport_packets_sent_total{server="metrics-test"} 10`,
+		`meteor_transport_payload_bytes_received_total{server="metrics-test"} 140`,
+	} {
+		assert.Contains(t, out, line+"\n")
+	}
+
+	_ = conn.Close()
+	assert.Eventually(t, func() bool {
+		return strings.Contains(scrape(t), `meteor_transport_closed_total{server="metrics-test",reason="closed"} 1`)
+	}, time.Second*5, time.Millisecond*20)
+	assert.Equal(t, int64(0), server.Metrics().Conns())
+
+	//停止后不再导出
+	_ = server.Stop()
+	assert.NotContains(t, scrape(t), `server="metrics-test"`)
+}
+
+func scrape(t *testing.T) string {
+	srv := httptest.NewServer(network.MetricsHandler())
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
@@ -1,30 +1,47 @@
 package transport
 
 import (
-	"go.uber.org/zap"
+	"errors"
+	"net"
 	"sync/atomic"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	"go.uber.org/zap"
 )
 
 type IMonitor interface {
 	Fmt()
 }
 
+// Monitor 单条连接的流量统计, 同时累加到所属服务端(或客户端)的 Metrics
 type Monitor struct {
-	InboundTraffic      uint64
+	InboundTraffic      atomic.Uint64
 	OutboundTraffic     atomic.Uint64
-	RealInboundTraffic  uint64
-	RealOutboundTraffic uint64
+	RealInboundTraffic  atomic.Uint64
+	RealOutboundTraffic atomic.Uint64
+
+	metrics *mnetwork.Metrics
 }
 
 func NewMonitor() *Monitor {
 	return &Monitor{}
 }
 
+// newMonitor needToMonitor 为 false 且 metrics 为空时返回 nil, 不做任何统计
+func newMonitor(needToMonitor bool, metrics *mnetwork.Metrics) *Monitor {
+	if !needToMonitor && metrics == nil {
+		return nil
+	}
+	return &Monitor{metrics: metrics}
+}
+
 func (m *Monitor) IncrementInboundTraffic(amount uint64) {
 	if m == nil {
 		return
 	}
-	m.incrementTraffic(amount, &m.InboundTraffic)
+	m.InboundTraffic.Add(amount)
+	m.metrics.IncrementInboundTraffic(amount)
 }
 
 func (m *Monitor) IncrementOutboundTraffic(amount uint64) {
@@ -32,6 +49,7 @@ func (m *Monitor) IncrementOutboundTraffic(amount uint64) {
 		return
 	}
 	m.OutboundTraffic.Add(amount)
+	m.metrics.IncrementOutboundTraffic(amount)
 }
 
 func (m *Monitor) GetOutboundTraffic() uint64 {
@@ -45,14 +63,16 @@ func (m *Monitor) IncrementRealInboundTraffic(amount uint64) {
 	if m == nil {
 		return
 	}
-	m.incrementTraffic(amount, &m.RealInboundTraffic)
+	m.RealInboundTraffic.Add(amount)
+	m.metrics.IncrementRealInboundTraffic(amount)
 }
 
 func (m *Monitor) IncrementRealOutboundTraffic(amount uint64) {
 	if m == nil {
 		return
 	}
-	m.incrementTraffic(amount, &m.RealOutboundTraffic)
+	m.RealOutboundTraffic.Add(amount)
+	m.metrics.IncrementRealOutboundTraffic(amount)
 }
 
 func (m *Monitor) Log() []zap.Field {
@@ -60,15 +80,56 @@ func (m *Monitor) Log() []zap.Field {
 		return nil
 	}
 	return []zap.Field{
-		zap.Uint64("InboundTraffic", m.InboundTraffic), zap.Uint64("OutboundTraffic", m.GetOutboundTraffic()),
-		zap.Uint64("RealOutboundTraffic", m.RealOutboundTraffic), zap.Uint64("RealInboundTraffic", m.RealInboundTraffic),
+		zap.Uint64("InboundTraffic", m.InboundTraffic.Load()), zap.Uint64("OutboundTraffic", m.GetOutboundTraffic()),
+		zap.Uint64("RealOutboundTraffic", m.RealOutboundTraffic.Load()), zap.Uint64("RealInboundTraffic", m.RealInboundTraffic.Load()),
+	}
+}
+
+// ObserveRTT 记录一次心跳往返时延
+func (m *Monitor) ObserveRTT(rtt time.Duration) {
+	if m == nil {
+		return
+	}
+	m.metrics.ObserveRTT(rtt)
+}
+
+// onOpen 连接建立, 与 onClose 成对调用
+func (m *Monitor) onOpen() {
+	if m == nil {
+		return
+	}
+	m.metrics.OnConnOpen()
+}
+
+// onClose 将连接关闭的原因计入 Metrics
+func (m *Monitor) onClose(err error) {
+	if m == nil {
+		return
 	}
+	m.metrics.OnConnClose(closeReason(err))
 }
 
-func (m *Monitor) incrementTraffic(amount uint64, outboundTraffic *uint64) {
-	if *outboundTraffic+amount < *outboundTraffic { // Check for overflow
-		*outboundTraffic = ^uint64(0) // Set to max value if overflow
-	} else {
-		*outboundTraffic += amount
+// closeReason 将连接关闭的原因归类为导出指标的 reason 标签值
+func closeReason(err error) string {
+	var ne net.Error
+	switch {
+	case err == nil, errors.Is(err, ErrCanceled):
+		return "closed"
+	case errors.Is(err, ErrIdleTimeout):
+		return "idle_timeout"
+	case errors.Is(err, ErrHeartbeatTimeout):
+		return "heartbeat_timeout"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrServerShutdown):
+		return "server_shutdown"
+	case errors.Is(err, ErrSlowConsumer):
+		return "slow_consumer"
+	case errors.Is(err, mnetwork.ErrSessionExpired):
+		return "session_expired"
+	case errors.As(err, &ne) && ne.Timeout():
+		return "timeout"
+	default:
+		return "error"
 	}
 }
@@ -0,0 +1,389 @@
+package transport
+
+import (
+	"context"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/orbit-w/meteor/bases/misc/number_utils"
+	"github.com/orbit-w/meteor/modules/mlog"
+	"go.uber.org/zap"
+)
+
+/*
+   @Author: orbit-w
+   @File: pool
+   @2026 10月 周一 10:20
+*/
+
+// BalanceStrategy 连接池为每次 Send 选择连接的策略
+type BalanceStrategy int8
+
+const (
+	BalanceRoundRobin     BalanceStrategy = iota //依次轮询所有已连接的连接
+	BalanceLeastQueued                           //选择发送队列积压字节数最少的连接
+	BalanceConsistentHash                        //按消息的 key 在地址的哈希环上选择, 相同 key 的消息发往同一条连接
+)
+
+// PoolOptions 客户端连接池配置
+type PoolOptions struct {
+	Size      int                          //每个地址保持的连接数, 0 时取 PoolSize
+	Strategy  BalanceStrategy              //选择连接的策略, 默认 BalanceRoundRobin
+	Replicas  int                          //一致性哈希时每个地址在哈希环上的虚拟节点数, 0 时取 PoolReplicas
+	Key       func(data []byte) []byte     //一致性哈希时从消息中取 key, 为空时以整条消息为 key
+	OnMessage func(addr string, in []byte) //收到消息的回调, 在各连接的接收协程中调用, in 只在回调返回前有效; 为空时丢弃收到的消息
+}
+
+// Pool 客户端连接池: 为每个后端地址保持 Size 条连接, 每次 Send 按 Strategy 选择一条连接发送.
+// Recv 返回错误的连接被移出连接池并关闭, 之后以指数退避重新拨号补足; 地址列表可以通过 Update 在运行时更新
+type Pool struct {
+	op       PoolOptions
+	ops      []Opt
+	handler  func(state ConnState) //调用方通过 WithStateHandler 设置的回调
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	next     atomic.Uint64
+	logger   *mlog.Logger
+	mu       sync.RWMutex
+	closed   bool
+	backends map[string]*poolBackend
+	slots    []*poolSlot //所有地址的连接槽位, 按地址排序
+	ring     []ringNode  //一致性哈希环, 按 hash 排序
+}
+
+type poolBackend struct {
+	addr   string
+	slots  []*poolSlot
+	cancel context.CancelFunc
+}
+
+// poolSlot 连接槽位, 连接被移出后到重新拨号之前为空
+type poolSlot struct {
+	addr string
+	conn atomic.Pointer[poolConn]
+}
+
+type poolConn struct {
+	IConn
+	ready       atomic.Bool //当前处于连接状态
+	established atomic.Bool //曾经连接成功
+}
+
+type ringNode struct {
+	hash    uint32
+	backend *poolBackend
+}
+
+// NewPool 创建连接池并向 addrs 中的每个地址建立 op.Size 条连接, _ops 为每条连接的拨号参数.
+// 与 DialWithOps 相同, 连接异步建立, 所有连接都未连接成功时 Send 写入仍在拨号的连接
+func NewPool(ctx context.Context, addrs []string, op *PoolOptions, _ops ...Opt) *Pool {
+	p := &Pool{
+		ops:      _ops,
+		backends: make(map[string]*poolBackend),
+		logger:   mlog.With(zap.String("TransportModel", "Pool")),
+	}
+	if op != nil {
+		p.op = *op
+	}
+	if p.op.Size <= 0 {
+		p.op.Size = PoolSize
+	}
+	if p.op.Replicas <= 0 {
+		p.op.Replicas = PoolReplicas
+	}
+	dp := DefaultDialOption()
+	parseOptions(dp, _ops...)
+	p.handler = dp.StateHandler
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.Update(addrs)
+	return p
+}
+
+// Send 按 Strategy 选择一条连接发送 data, 连接池中没有连接时返回 ErrNoAvailableConn
+func (p *Pool) Send(data []byte) error {
+	if p.op.Strategy == BalanceConsistentHash {
+		key := data
+		if p.op.Key != nil {
+			key = p.op.Key(data)
+		}
+		return p.SendWithKey(key, data)
+	}
+
+	pc, err := p.pick()
+	if err != nil {
+		return err
+	}
+	return pc.Send(data)
+}
+
+// SendWithKey 在哈希环上选择 key 对应的连接发送 data, 与 Strategy 无关.
+// key 对应的地址没有已连接的连接时沿哈希环选择下一个地址, 此时不保证相同 key 的消息之间的顺序
+func (p *Pool) SendWithKey(key, data []byte) error {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	pc := p.locate(crc32.ChecksumIEEE(key))
+	p.mu.RUnlock()
+	if pc == nil {
+		return ErrNoAvailableConn
+	}
+	return pc.Send(data)
+}
+
+// Update 更新后端地址列表: 向新增的地址建立连接, 关闭已移除地址的连接, 保留的地址不受影响
+func (p *Pool) Update(addrs []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+
+	keep := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		keep[addr] = struct{}{}
+		if _, ok := p.backends[addr]; !ok {
+			p.backends[addr] = p.startBackend(addr)
+		}
+	}
+	for addr, b := range p.backends {
+		if _, ok := keep[addr]; !ok {
+			b.cancel()
+			delete(p.backends, addr)
+		}
+	}
+	p.rebuild()
+}
+
+// Addrs 当前的后端地址列表, 按地址排序
+func (p *Pool) Addrs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addrs := make([]string, 0, len(p.backends))
+	for addr := range p.backends {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Len 处于连接状态的连接数
+func (p *Pool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var n int
+	for _, s := range p.slots {
+		if pc := s.conn.Load(); pc != nil && pc.ready.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// Close 关闭连接池中的所有连接, 阻塞直到所有接收协程退出
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.backends = nil
+	p.slots = nil
+	p.ring = nil
+	p.mu.Unlock()
+
+	p.cancel()
+	p.wg.Wait()
+	return nil
+}
+
+func (p *Pool) startBackend(addr string) *poolBackend {
+	ctx, cancel := context.WithCancel(p.ctx)
+	b := &poolBackend{
+		addr:   addr,
+		slots:  make([]*poolSlot, p.op.Size),
+		cancel: cancel,
+	}
+	for i := range b.slots {
+		s := &poolSlot{addr: addr}
+		b.slots[i] = s
+		p.wg.Add(1)
+		go p.keep(ctx, s)
+	}
+	return b
+}
+
+// rebuild 地址列表变化后重建槽位列表与哈希环, 调用方需持有 mu
+func (p *Pool) rebuild() {
+	addrs := make([]string, 0, len(p.backends))
+	for addr := range p.backends {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	p.slots = p.slots[:0]
+	p.ring = p.ring[:0]
+	for _, addr := range addrs {
+		b := p.backends[addr]
+		p.slots = append(p.slots, b.slots...)
+		for i := 0; i < p.op.Replicas; i++ {
+			p.ring = append(p.ring, ringNode{
+				hash:    crc32.ChecksumIEEE([]byte(addr + "#" + strconv.Itoa(i))),
+				backend: b,
+			})
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool {
+		return p.ring[i].hash < p.ring[j].hash
+	})
+}
+
+// keep 维持槽位上的连接: 连接的 Recv 返回错误时移出并关闭该连接, 以指数退避重新拨号, 直到地址被移除或连接池关闭
+func (p *Pool) keep(ctx context.Context, s *poolSlot) {
+	defer p.wg.Done()
+	var retried int
+	for {
+		pc := new(poolConn)
+		ops := append(p.ops[:len(p.ops):len(p.ops)], WithStateHandler(func(state ConnState) {
+			switch state {
+			case ConnStateConnected, ConnStateReconnected:
+				pc.established.Store(true)
+				pc.ready.Store(true)
+			default:
+				pc.ready.Store(false)
+			}
+			if p.handler != nil {
+				p.handler(state)
+			}
+		}))
+		pc.IConn = DialWithOps(ctx, s.addr, ops...)
+		s.conn.Store(pc)
+
+		err := p.recv(ctx, s.addr, pc)
+		s.conn.CompareAndSwap(pc, nil)
+		_ = pc.Close()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if pc.established.Load() {
+			retried = 0
+		}
+		p.logger.Info("Pool conn evicted", zap.String("RemoteAddr", s.addr), zap.Error(err))
+		backoff := time.Millisecond * time.Duration(100<<number_utils.Min[int](retried, MaxRetried))
+		retried++
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *Pool) recv(ctx context.Context, addr string, pc *poolConn) error {
+	for {
+		msg, err := RecvMessage(ctx, pc.IConn)
+		if err != nil {
+			return err
+		}
+		if p.op.OnMessage != nil {
+			p.op.OnMessage(addr, msg.Data)
+		}
+		msg.Release()
+	}
+}
+
+func (p *Pool) pick() (*poolConn, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	var pc *poolConn
+	start := p.next.Add(1)
+	switch p.op.Strategy {
+	case BalanceLeastQueued:
+		pc = leastQueued(p.slots, start)
+	default:
+		pc = scan(p.slots, start)
+	}
+	if pc == nil {
+		return nil, ErrNoAvailableConn
+	}
+	return pc, nil
+}
+
+// locate 从 hash 在哈希环上的位置开始顺时针查找有已连接的连接的地址, 都没有时返回第一条仍在拨号的连接, 调用方需持有 mu
+func (p *Pool) locate(hash uint32) *poolConn {
+	n := len(p.ring)
+	i := sort.Search(n, func(i int) bool {
+		return p.ring[i].hash >= hash
+	})
+	var fallback *poolConn
+	for j := 0; j < n; j++ {
+		pc := scan(p.ring[(i+j)%n].backend.slots, uint64(hash))
+		if pc == nil {
+			continue
+		}
+		if pc.ready.Load() {
+			return pc
+		}
+		if fallback == nil {
+			fallback = pc
+		}
+	}
+	return fallback
+}
+
+// scan 从 start 开始遍历 slots, 返回第一条已连接的连接; 都未连接时返回第一条仍在拨号的连接
+func scan(slots []*poolSlot, start uint64) *poolConn {
+	var fallback *poolConn
+	n := uint64(len(slots))
+	for i := uint64(0); i < n; i++ {
+		pc := slots[(start+i)%n].conn.Load()
+		if pc == nil {
+			continue
+		}
+		if pc.ready.Load() {
+			return pc
+		}
+		if fallback == nil {
+			fallback = pc
+		}
+	}
+	return fallback
+}
+
+// leastQueued 返回发送队列积压字节数最少的已连接的连接, 积压相同时从 start 开始轮询
+func leastQueued(slots []*poolSlot, start uint64) *poolConn {
+	var (
+		best   *poolConn
+		queued int
+	)
+	n := uint64(len(slots))
+	for i := uint64(0); i < n; i++ {
+		pc := slots[(start+i)%n].conn.Load()
+		if pc == nil || !pc.ready.Load() {
+			continue
+		}
+		var q int
+		if bp, ok := pc.IConn.(IBackpressure); ok {
+			q = bp.QueuedBytes()
+		}
+		if best == nil || q < queued {
+			best, queued = pc, q
+		}
+	}
+	if best == nil {
+		return scan(slots, start)
+	}
+	return best
+}
@@ -0,0 +1,273 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: pool_test
+   @2026 10月 周一 10:50
+*/
+
+// poolBackendServer 记录收到的消息以及收到消息的连接
+type poolBackendServer struct {
+	IServer
+	mu       sync.Mutex
+	received map[string]uint64 //消息 -> 连接 ID
+	count    atomic.Int32
+}
+
+func servePoolBackend(t *testing.T, echo bool) *poolBackendServer {
+	s := &poolBackendServer{received: make(map[string]uint64)}
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.received[string(in)] = conn.(ISession).ID()
+			s.mu.Unlock()
+			s.count.Add(1)
+			if echo {
+				_ = conn.Send(in)
+			}
+		}
+	}, DefaultServerConfig())
+	assert.NoError(t, err)
+	s.IServer = server
+	return s
+}
+
+func (s *poolBackendServer) sessionOf(msg string) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.received[msg]
+	return id, ok
+}
+
+func waitPoolReady(t *testing.T, pool *Pool, n int) {
+	assert.Eventually(t, func() bool {
+		return pool.Len() == n
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func Test_PoolRoundRobin(t *testing.T) {
+	a, b := servePoolBackend(t, false), servePoolBackend(t, false)
+	defer a.Stop()
+	defer b.Stop()
+
+	pool := NewPool(context.Background(), []string{a.Addr(), b.Addr()}, &PoolOptions{Size: 2}, WithProtocol(network.MEM))
+	defer pool.Close()
+	waitPoolReady(t, pool, 4)
+
+	for i := 0; i < 40; i++ {
+		assert.NoError(t, pool.Send([]byte(fmt.Sprint(i))))
+	}
+	assert.Eventually(t, func() bool {
+		return a.count.Load()+b.count.Load() == 40
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, int32(20), a.count.Load())
+	assert.Equal(t, int32(20), b.count.Load())
+	assert.Equal(t, 2, a.Sessions().CCU())
+	assert.Equal(t, 2, b.Sessions().CCU())
+}
+
+func Test_PoolConsistentHash(t *testing.T) {
+	a, b := servePoolBackend(t, false), servePoolBackend(t, false)
+	defer a.Stop()
+	defer b.Stop()
+
+	//消息格式 key:seq, 以 key 为一致性哈希的 key
+	pool := NewPool(context.Background(), []string{a.Addr(), b.Addr()}, &PoolOptions{
+		Size:     2,
+		Strategy: BalanceConsistentHash,
+		Key: func(data []byte) []byte {
+			for i := range data {
+				if data[i] == ':' {
+					return data[:i]
+				}
+			}
+			return data
+		},
+	}, WithProtocol(network.MEM))
+	defer pool.Close()
+	waitPoolReady(t, pool, 4)
+
+	const keys, rounds = 32, 5
+	for r := 0; r < rounds; r++ {
+		for k := 0; k < keys; k++ {
+			assert.NoError(t, pool.Send([]byte(fmt.Sprintf("user-%d:%d", k, r))))
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return a.count.Load()+b.count.Load() == keys*rounds
+	}, time.Second*5, time.Millisecond*10)
+
+	//相同 key 的消息发往同一条连接, 不同的 key 分布在两个地址上
+	for k := 0; k < keys; k++ {
+		server := a
+		if _, ok := a.sessionOf(fmt.Sprintf("user-%d:0", k)); !ok {
+			server = b
+		}
+		first, _ := server.sessionOf(fmt.Sprintf("user-%d:0", k))
+		for r := 1; r < rounds; r++ {
+			id, ok := server.sessionOf(fmt.Sprintf("user-%d:%d", k, r))
+			assert.True(t, ok)
+			assert.Equal(t, first, id)
+		}
+	}
+	assert.NotZero(t, a.count.Load())
+	assert.NotZero(t, b.count.Load())
+}
+
+// queuedConn 积压字节数固定的连接
+type queuedConn struct {
+	IConn
+	queued int
+}
+
+func (c *queuedConn) SendContext(context.Context, []byte) error {
+	return nil
+}
+
+func (c *queuedConn) QueuedBytes() int {
+	return c.queued
+}
+
+func Test_PoolLeastQueued(t *testing.T) {
+	slot := func(queued int, ready bool) *poolSlot {
+		pc := &poolConn{IConn: &queuedConn{queued: queued}}
+		pc.ready.Store(ready)
+		s := new(poolSlot)
+		s.conn.Store(pc)
+		return s
+	}
+	queuedOf := func(pc *poolConn) int {
+		return pc.IConn.(*queuedConn).queued
+	}
+
+	slots := []*poolSlot{slot(300, true), slot(100, true), slot(0, false), slot(200, true), new(poolSlot)}
+	for start := uint64(0); start < 5; start++ {
+		assert.Equal(t, 100, queuedOf(leastQueued(slots, start)))
+	}
+
+	//积压相同时轮询
+	slots = []*poolSlot{slot(0, true), slot(0, true)}
+	assert.NotSame(t, leastQueued(slots, 0), leastQueued(slots, 1))
+
+	//都未连接时选择仍在拨号的连接
+	slots = []*poolSlot{new(poolSlot), slot(50, false)}
+	assert.Equal(t, 50, queuedOf(leastQueued(slots, 0)))
+	assert.Nil(t, leastQueued([]*poolSlot{new(poolSlot)}, 0))
+}
+
+func Test_PoolEvict(t *testing.T) {
+	//服务端关闭最先建立的两条连接
+	var handled atomic.Int32
+	received := make(chan string, 16)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		if handled.Add(1) <= 2 {
+			_ = conn.Close()
+			return
+		}
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			received <- string(in)
+		}
+	}, DefaultServerConfig())
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	pool := NewPool(context.Background(), []string{server.Addr()}, &PoolOptions{Size: 2}, WithProtocol(network.MEM))
+	defer pool.Close()
+
+	//被关闭的连接移出连接池后重新拨号补足
+	assert.Eventually(t, func() bool {
+		return handled.Load() == 4 && pool.Len() == 2
+	}, time.Second*5, time.Millisecond*10)
+	assert.NoError(t, pool.Send([]byte("hello")))
+	select {
+	case in := <-received:
+		assert.Equal(t, "hello", in)
+	case <-time.After(time.Second * 5):
+		t.Fatal("message not received")
+	}
+}
+
+func Test_PoolUpdate(t *testing.T) {
+	a, b := servePoolBackend(t, false), servePoolBackend(t, false)
+	defer a.Stop()
+	defer b.Stop()
+
+	pool := NewPool(context.Background(), []string{a.Addr()}, &PoolOptions{Size: 2}, WithProtocol(network.MEM))
+	defer pool.Close()
+	waitPoolReady(t, pool, 2)
+
+	pool.Update([]string{a.Addr(), b.Addr()})
+	waitPoolReady(t, pool, 4)
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, pool.Send([]byte(fmt.Sprint(i))))
+	}
+	assert.Eventually(t, func() bool {
+		return a.count.Load() == 4 && b.count.Load() == 4
+	}, time.Second*5, time.Millisecond*10)
+
+	//移除的地址上的连接被关闭
+	pool.Update([]string{b.Addr()})
+	assert.Equal(t, []string{b.Addr()}, pool.Addrs())
+	assert.Eventually(t, func() bool {
+		return a.Sessions().CCU() == 0
+	}, time.Second*5, time.Millisecond*10)
+	for i := 8; i < 12; i++ {
+		assert.NoError(t, pool.Send([]byte(fmt.Sprint(i))))
+	}
+	assert.Eventually(t, func() bool {
+		return b.count.Load() == 8
+	}, time.Second*5, time.Millisecond*10)
+	assert.Equal(t, int32(4), a.count.Load())
+
+	pool.Update(nil)
+	assert.ErrorIs(t, pool.Send([]byte("hello")), ErrNoAvailableConn)
+}
+
+func Test_PoolOnMessage(t *testing.T) {
+	a := servePoolBackend(t, true)
+	defer a.Stop()
+
+	replies := make(chan string, 1)
+	pool := NewPool(context.Background(), []string{a.Addr()}, &PoolOptions{
+		Size: 1,
+		OnMessage: func(addr string, in []byte) {
+			replies <- addr + "|" + string(in)
+		},
+	}, WithProtocol(network.MEM))
+	waitPoolReady(t, pool, 1)
+
+	assert.NoError(t, pool.Send([]byte("hello")))
+	select {
+	case reply := <-replies:
+		assert.Equal(t, a.Addr()+"|hello", reply)
+	case <-time.After(time.Second * 5):
+		t.Fatal("reply not received")
+	}
+
+	assert.NoError(t, pool.Close())
+	assert.Equal(t, 0, pool.Len())
+	assert.ErrorIs(t, pool.Send([]byte("hello")), ErrPoolClosed)
+	assert.Eventually(t, func() bool {
+		return a.Sessions().CCU() == 0
+	}, time.Second*5, time.Millisecond*10)
+}
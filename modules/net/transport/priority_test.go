@@ -0,0 +1,120 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: priority_test
+   @2026 10月 周日 18:20
+*/
+
+// laneLen 返回 p 通道中尚未交给发送协程的消息数
+func (g *gatedBuffer) laneLen(p Priority) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.lanes[p].length
+}
+
+// waitPicked 等待 p 通道中的消息被 flush 取走
+func (g *gatedBuffer) waitPicked(t *testing.T, p Priority) {
+	assert.Eventually(t, func() bool { return g.laneLen(p) == 0 }, time.Second*5, time.Millisecond)
+}
+
+func Test_PriorityUrgentFirst(t *testing.T) {
+	//设置高水位使得同一时间只有一个批次在发送
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 1 << 20}, nil)
+	defer g.OnClose()
+	defer close(g.gate)
+
+	assert.NoError(t, g.Set(message('a')))
+	g.waitPicked(t, PriorityBulk)
+	assert.NoError(t, g.Set(message('b')))
+	assert.NoError(t, g.SetWithPriority(message('u'), PriorityUrgent))
+
+	g.gate <- struct{}{}
+	assert.Equal(t, string(message('a')), g.recv(t))
+	g.gate <- struct{}{}
+	assert.Equal(t, string(message('u')), g.recv(t))
+	g.gate <- struct{}{}
+	assert.Equal(t, string(message('b')), g.recv(t))
+}
+
+func Test_PriorityNoStarvation(t *testing.T) {
+	g := newGatedBuffer(network.SendQueueOptions{HighWatermark: 1 << 20}, nil)
+	defer g.OnClose()
+	defer close(g.gate)
+
+	assert.NoError(t, g.Set(message('a')))
+	g.waitPicked(t, PriorityBulk)
+	assert.NoError(t, g.Set(message('b')))
+
+	//每发送一个批次补充一条 urgent 消息, urgent 通道始终有积压
+	prev := message('a')
+	for i := 0; i < UrgentBurst; i++ {
+		urgent := message(byte('0' + i))
+		assert.NoError(t, g.SetWithPriority(urgent, PriorityUrgent))
+		g.gate <- struct{}{}
+		assert.Equal(t, string(prev), g.recv(t))
+		g.waitPicked(t, PriorityUrgent)
+		prev = urgent
+	}
+
+	//连续 UrgentBurst 个 urgent 批次之后发送一个 bulk 批次
+	last := message(byte('0' + UrgentBurst))
+	assert.NoError(t, g.SetWithPriority(last, PriorityUrgent))
+	g.gate <- struct{}{}
+	assert.Equal(t, string(prev), g.recv(t))
+	g.waitPicked(t, PriorityBulk)
+	g.gate <- struct{}{}
+	assert.Equal(t, string(message('b')), g.recv(t))
+	g.gate <- struct{}{}
+	assert.Equal(t, string(last), g.recv(t))
+}
+
+func Test_SendWithPriority(t *testing.T) {
+	const num = 200
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		sender := conn.(IPrioritySender)
+		for i := 0; i < num; i++ {
+			_ = sender.SendWithPriority([]byte(fmt.Sprintf("bulk-%d", i)), PriorityBulk)
+			_ = sender.SendWithPriority([]byte(fmt.Sprintf("urgent-%d", i)), PriorityUrgent)
+		}
+		_, _ = conn.Recv(context.Background())
+	}, DefaultServerConfig())
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//不同通道之间不保证顺序, 同一通道内保持顺序
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	var bulk, urgent int
+	for i := 0; i < 2*num; i++ {
+		data, err := conn.Recv(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if strings.HasPrefix(string(data), "urgent-") {
+			assert.Equal(t, fmt.Sprintf("urgent-%d", urgent), string(data))
+			urgent++
+		} else {
+			assert.Equal(t, fmt.Sprintf("bulk-%d", bulk), string(data))
+			bulk++
+		}
+	}
+	assert.Equal(t, num, bulk)
+	assert.Equal(t, num, urgent)
+}
@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: reconnect_test
+   @2026 10月 周日 14:05
+*/
+
+func Test_Reconnect(t *testing.T) {
+	server := serveKickable(t, "127.0.0.1:0")
+	host := server.Addr()
+
+	var (
+		mu     sync.Mutex
+		states []ConnState
+	)
+	conn := DialWithOps(context.Background(), host, WithReconnect(1024), WithStateHandler(func(state ConnState) {
+		mu.Lock()
+		states = append(states, state)
+		mu.Unlock()
+	}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("hello")))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(in))
+
+	//停服并踢掉连接, 断线期间的数据缓存在 ControlBuffer 中
+	_ = server.Stop()
+	assert.NoError(t, conn.Send([]byte("bye")))
+	waitState(t, &mu, &states, ConnStateDisconnected)
+	assert.NoError(t, conn.Send([]byte("buffered")))
+	assert.ErrorIs(t, conn.Send(make([]byte, 1024)), ErrSendQueueFull)
+
+	server = serveKickable(t, host)
+	defer server.Stop()
+
+	in, err = conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "buffered", string(in))
+	waitState(t, &mu, &states, ConnStateReconnected)
+
+	mu.Lock()
+	assert.Equal(t, ConnStateConnecting, states[0])
+	assert.Equal(t, ConnStateConnected, states[1])
+	mu.Unlock()
+}
+
+func Test_ReconnectClose(t *testing.T) {
+	server := serveKickable(t, "127.0.0.1:0")
+	conn := DialWithOps(context.Background(), server.Addr(), WithReconnect(0), WithBlock(true))
+	assert.NoError(t, conn.Send([]byte("bye")))
+	_ = server.Stop()
+
+	time.Sleep(time.Millisecond * 200)
+	_ = conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err := conn.Recv(ctx)
+	assert.ErrorIs(t, err, ErrCanceled)
+}
+
+// serveKickable echo 服务端, 收到 "bye" 时主动断开连接
+func serveKickable(t *testing.T, host string) IServer {
+	server, err := Serve("tcp", host, func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			if string(in) == "bye" {
+				_ = conn.Close()
+				return
+			}
+			_ = conn.Send(in)
+		}
+	})
+	assert.NoError(t, err)
+	return server
+}
+
+func waitState(t *testing.T, mu *sync.Mutex, states *[]ConnState, state ConnState) {
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		for _, s := range *states {
+			if s == state {
+				mu.Unlock()
+				return
+			}
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond * 50)
+	}
+	t.Fatalf("conn state %s not reached", state)
+}
@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: recv_test
+   @2026 10月 周日 14:52
+*/
+
+func Test_RecvMessage(t *testing.T) {
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		receiver := conn.(IMessageReceiver)
+		for {
+			msg, err := receiver.RecvMessage(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(msg.Data)
+			msg.Release()
+		}
+	}, DefaultServerConfig())
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	const num = 1000
+	for i := 0; i < num; i++ {
+		assert.NoError(t, conn.Send([]byte(fmt.Sprintf("message-%d", i))))
+	}
+
+	//持有全部消息直到最后再释放, 后续帧不会覆盖尚未释放的消息
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	receiver := conn.(IMessageReceiver)
+	msgs := make([]network.Message, 0, num)
+	for i := 0; i < num; i++ {
+		msg, err := receiver.RecvMessage(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+	for i, msg := range msgs {
+		assert.Equal(t, fmt.Sprintf("message-%d", i), string(msg.Data))
+		msg.Release()
+	}
+}
+
+func Test_RecvMessageClosed(t *testing.T) {
+	server := serveEcho(t, DefaultServerConfig())
+	conn := DialWithOps(context.Background(), server.Addr())
+	assertEcho(t, conn, "hello")
+	_ = conn.Close()
+	_ = server.Stop()
+
+	_, err := conn.(IMessageReceiver).RecvMessage(context.Background())
+	assert.Error(t, err)
+}
+
+func Test_RecvReleasesBuffer(t *testing.T) {
+	r := network.NewBlockReceiver()
+	buf := packet.GetBuffer(5)
+	copy(buf.Bytes(), "hello")
+	buf.Retain()
+	r.PutMessage(network.NewMessage(buf.Bytes()[:2], buf))
+	r.PutMessage(network.NewMessage(buf.Bytes()[2:], buf))
+
+	//Recv 拷贝消息后释放引用, 帧内的消息都被接收后缓冲区归还到缓冲池
+	in, err := r.Recv(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "he", string(in))
+	assert.Equal(t, int32(1), buf.Refs())
+	in, err = r.Recv(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "llo", string(in))
+	assert.Equal(t, int32(0), buf.Refs())
+}
+
+func Test_RecvMessageFallback(t *testing.T) {
+	server := serveEcho(t, DefaultServerConfig())
+	defer server.Stop()
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//包装后的连接不实现 IMessageReceiver 时退化为 Recv
+	wrapped := struct{ IConn }{conn}
+	assert.NoError(t, wrapped.Send([]byte("hello")))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	msg, err := RecvMessage(ctx, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(msg.Data))
+	msg.Release()
+}
@@ -0,0 +1,349 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+)
+
+/*
+   @Author: orbit-w
+   @File: resume
+   @2026 10月 周一 00:10
+*/
+
+// resumeTokenSize 会话令牌的字节数
+const resumeTokenSize = 16
+
+// replayBatch 重放窗口中的一个发送批次: size<int32> | data | size<int32> | data ...
+type replayBatch struct {
+	seq   uint64 //批次中第一条消息的序号
+	count int
+	data  []byte
+}
+
+// replayWindow 重放窗口: 按写入连接的顺序保存对端尚未确认的批次, 每条消息分配从 1 开始递增的序号.
+// 保存的字节数超过 max 时丢弃最早的批次, 对端缺失这些消息时无法恢复会话
+type replayWindow struct {
+	mu      sync.Mutex
+	max     int
+	size    int
+	next    uint64 //下一条消息的序号
+	batches []replayBatch
+}
+
+func newReplayWindow(max int) *replayWindow {
+	return &replayWindow{max: max, next: 1}
+}
+
+// push 保存一个批次的拷贝, 在批次写入连接之前调用
+func (w *replayWindow) push(data []byte) {
+	count := countItems(data)
+	if count == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, replayBatch{seq: w.next, count: count, data: append([]byte(nil), data...)})
+	w.next += uint64(count)
+	w.size += len(data)
+	for w.size > w.max && len(w.batches) > 0 {
+		w.drop()
+	}
+}
+
+// ack 对端确认收到了 seq 及之前的消息, 移除全部消息都已确认的批次
+func (w *replayWindow) ack(seq uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.batches) > 0 && w.batches[0].seq+uint64(w.batches[0].count) <= seq+1 {
+		w.drop()
+	}
+}
+
+// since 返回序号大于 seq 的消息, 按批次组织; 对端缺失的消息已被移出窗口时返回 ErrReplayWindowExceeded
+func (w *replayWindow) since(seq uint64) ([][]byte, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	base := w.next
+	if len(w.batches) > 0 {
+		base = w.batches[0].seq
+	}
+	if seq+1 < base || seq >= w.next {
+		return nil, mnetwork.ErrReplayWindowExceeded
+	}
+
+	var out [][]byte
+	for _, b := range w.batches {
+		if b.seq+uint64(b.count) <= seq+1 {
+			continue
+		}
+		data := b.data
+		//跳过批次中对端已收到的消息
+		for s := b.seq; s <= seq; s++ {
+			_, data, _ = nextItem(data)
+		}
+		out = append(out, data)
+	}
+	return out, nil
+}
+
+// pending 窗口中保存的字节数
+func (w *replayWindow) pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.size
+}
+
+func (w *replayWindow) drop() {
+	w.size -= len(w.batches[0].data)
+	w.batches[0] = replayBatch{}
+	w.batches = w.batches[1:]
+}
+
+// countItems 批次中的消息数
+func countItems(data []byte) int {
+	var n int
+	for len(data) > 0 {
+		_, remain, ok := nextItem(data)
+		if !ok {
+			break
+		}
+		data = remain
+		n++
+	}
+	return n
+}
+
+// resumeState 可恢复会话两端共用的状态
+type resumeState struct {
+	token   []byte
+	window  *replayWindow
+	recvSeq uint64 //已收到的最后一条消息的序号, 只在读协程中访问
+	unacked int    //上次回复 SeqAck 之后收到的消息字节数
+}
+
+func (rs *resumeState) onRecv(n int) {
+	rs.recvSeq++
+	rs.unacked += n
+}
+
+// ackDue 上次确认之后收到的消息超过 ResumeAckBytes 时返回 true, 由读协程回复 SeqAck
+func (rs *resumeState) ackDue() bool {
+	if rs.unacked < ResumeAckBytes {
+		return false
+	}
+	rs.unacked = 0
+	return true
+}
+
+// onSeqAck 对端确认已收到的消息序号, 清理重放窗口
+func (rs *resumeState) onSeqAck(data []byte) {
+	if seq, err := mnetwork.DecodeSeqAck(data); err == nil {
+		rs.window.ack(seq)
+	}
+}
+
+// resumeLink 客户端恢复会话时建立的新连接
+type resumeLink struct {
+	conn    net.Conn
+	codec   *mnetwork.Codec
+	recvSeq uint64        //客户端已收到的最后一条消息的序号
+	done    chan struct{} //连接从会话上断开或者会话结束时关闭
+}
+
+// serverResume 服务端可恢复会话的状态
+type serverResume struct {
+	resumeState
+	op       *mnetwork.AcceptorOptions
+	grace    time.Duration
+	link     *resumeLink //当前恢复的连接, 只在读协程中访问
+	detached atomic.Bool //连接断开, 等待客户端恢复
+	attach   chan *resumeLink
+	closed   chan struct{} //本端调用 Close 或者客户端主动关闭, 不再等待恢复
+	once     sync.Once
+}
+
+func newServerResume(op *mnetwork.AcceptorOptions) *serverResume {
+	grace := op.Resume.GracePeriod
+	if grace <= 0 {
+		grace = ResumeGracePeriod
+	}
+	size := op.Resume.WindowSize
+	if size <= 0 {
+		size = ResumeWindowSize
+	}
+	token := make([]byte, resumeTokenSize)
+	_, _ = rand.Read(token)
+	return &serverResume{
+		resumeState: resumeState{
+			token:  token,
+			window: newReplayWindow(size),
+		},
+		op:     op,
+		grace:  grace,
+		attach: make(chan *resumeLink),
+		closed: make(chan struct{}),
+	}
+}
+
+func (rs *serverResume) close() {
+	rs.once.Do(func() {
+		close(rs.closed)
+	})
+}
+
+func (rs *serverResume) isClosed() bool {
+	select {
+	case <-rs.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (rs *serverResume) detach() {
+	rs.detached.Store(true)
+	rs.release()
+}
+
+func (rs *serverResume) attached(l *resumeLink) {
+	rs.link = l
+	rs.detached.Store(false)
+}
+
+// release 通知当前恢复的连接的处理协程退出
+func (rs *serverResume) release() {
+	if rs.link != nil {
+		close(rs.link.done)
+		rs.link = nil
+	}
+}
+
+// resumeRegistry 可恢复会话的注册表, 以会话令牌索引; 会话从建立到最终关闭(包括等待恢复期间)都在表中
+type resumeRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*TcpServerConn
+}
+
+var resumeSessions = &resumeRegistry{sessions: make(map[string]*TcpServerConn)}
+
+func (r *resumeRegistry) add(ts *TcpServerConn) {
+	r.mu.Lock()
+	r.sessions[string(ts.resume.token)] = ts
+	r.mu.Unlock()
+}
+
+// get 查找 op 对应的服务端上 token 对应的会话
+func (r *resumeRegistry) get(token []byte, op *mnetwork.AcceptorOptions) (*TcpServerConn, bool) {
+	r.mu.Lock()
+	ts, ok := r.sessions[string(token)]
+	r.mu.Unlock()
+	if !ok || ts.resume.op != op {
+		return nil, false
+	}
+	return ts, true
+}
+
+func (r *resumeRegistry) remove(ts *TcpServerConn) {
+	r.mu.Lock()
+	delete(r.sessions, string(ts.resume.token))
+	r.mu.Unlock()
+}
+
+// readResume 读取客户端的会话恢复请求, 超过 HandshakeTimeout 未收到时关闭连接
+func readResume(ctx context.Context, conn net.Conn, codec *mnetwork.Codec, head, body []byte) (*mnetwork.ResumeRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, HandshakeTimeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+
+	data, h, err := codec.BlockDecodeBody(conn, head, body)
+	if !stop() {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, mnetwork.ErrHandshakeTimeout
+		}
+		return nil, ErrCanceled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h != mnetwork.TypeMessageResume {
+		return nil, mnetwork.ErrResumeFailed
+	}
+	return mnetwork.DecodeResumeRequest(data)
+}
+
+// resumeSession 将新连接交给令牌对应的会话, 阻塞直到该连接再次断开或者会话结束
+func resumeSession(_conn net.Conn, ac *acceptedConn, op *mnetwork.AcceptorOptions) error {
+	ts, ok := resumeSessions.get(ac.resume.Token, op)
+	if !ok {
+		ack := mnetwork.EncodeResumeAck(&mnetwork.ResumeAck{}, mnetwork.ErrSessionExpired)
+		_ = writeFrame(_conn, ac.codec, ack, mnetwork.TypeMessageResumeAck)
+		return mnetwork.ErrSessionExpired
+	}
+
+	l := &resumeLink{
+		conn:    _conn,
+		codec:   ac.codec,
+		recvSeq: ac.resume.RecvSeq,
+		done:    make(chan struct{}),
+	}
+	//客户端切换网络后旧连接可能还没有断开(半开连接), 关闭旧连接使会话进入等待恢复状态
+	if conn, _ := ts.link(); conn != _conn {
+		_ = conn.Close()
+	}
+
+	timer := time.NewTimer(HandshakeTimeout)
+	defer timer.Stop()
+	select {
+	case ts.resume.attach <- l:
+	case <-ts.done:
+		return mnetwork.ErrSessionExpired
+	case <-timer.C:
+		return mnetwork.ErrHandshakeTimeout
+	}
+	<-l.done
+	return nil
+}
+
+// sendResume 发送会话恢复请求并等待服务端应答, 服务端拒绝时返回 ErrSessionExpired 或者 ErrReplayWindowExceeded
+func sendResume(conn net.Conn, codec *mnetwork.Codec, req *mnetwork.ResumeRequest) (*mnetwork.ResumeAck, error) {
+	if err := writeFrame(conn, codec, req.Encode(), mnetwork.TypeMessageResume); err != nil {
+		return nil, err
+	}
+
+	data, buf, h, err := codec.BlockDecodeBuffer(conn, make([]byte, HeadLen))
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Release()
+	if h != mnetwork.TypeMessageResumeAck {
+		return nil, mnetwork.ErrResumeFailed
+	}
+	return mnetwork.DecodeResumeAck(data)
+}
+
+// replay 在新连接上重放对端未收到的批次
+func replay(conn net.Conn, codec *mnetwork.Codec, batches [][]byte) error {
+	for _, batch := range batches {
+		if err := writeFrame(conn, codec, batch, mnetwork.TypeMessageRaw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isFatalDialErr 握手被拒绝或者会话无法恢复时重试没有意义
+func isFatalDialErr(err error) bool {
+	return errors.Is(err, mnetwork.ErrHandshakeRejected) ||
+		errors.Is(err, mnetwork.ErrSessionExpired) ||
+		errors.Is(err, mnetwork.ErrReplayWindowExceeded)
+}
@@ -0,0 +1,350 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: resume_test
+   @2026 10月 周一 00:40
+*/
+
+// replayItems 将批次展开为消息
+func replayItems(batches [][]byte) []string {
+	var items []string
+	for _, batch := range batches {
+		for len(batch) > 0 {
+			item, remain, ok := nextItem(batch)
+			if !ok {
+				break
+			}
+			items = append(items, string(item))
+			batch = remain
+		}
+	}
+	return items
+}
+
+func replayBatchOf(items ...string) []byte {
+	var batch []byte
+	for _, item := range items {
+		batch = append(batch, encodeItem([]byte(item)).Data()...)
+	}
+	return batch
+}
+
+func Test_ReplayWindow(t *testing.T) {
+	w := newReplayWindow(64)
+	w.push(replayBatchOf("a", "b"))
+	w.push(replayBatchOf("c"))
+	w.push(replayBatchOf("d", "e"))
+
+	batches, err := w.since(1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "d", "e"}, replayItems(batches))
+
+	//确认之后已确认的批次被移出窗口
+	w.ack(2)
+	_, err = w.since(1)
+	assert.ErrorIs(t, err, network.ErrReplayWindowExceeded)
+	batches, err = w.since(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "d", "e"}, replayItems(batches))
+
+	batches, err = w.since(5)
+	assert.NoError(t, err)
+	assert.Empty(t, batches)
+	_, err = w.since(6)
+	assert.ErrorIs(t, err, network.ErrReplayWindowExceeded)
+
+	//超过 max 时丢弃最早的批次
+	w.push(replayBatchOf(strings.Repeat("f", 60)))
+	assert.LessOrEqual(t, w.pending(), 64)
+	_, err = w.since(2)
+	assert.ErrorIs(t, err, network.ErrReplayWindowExceeded)
+	batches, err = w.since(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{strings.Repeat("f", 60)}, replayItems(batches))
+}
+
+// dropClient 模拟客户端切换网络: 直接关闭底层连接, 不通知服务端
+func dropClient(conn IConn) {
+	tc := conn.(*TcpClient)
+	tc.connCond.L.Lock()
+	c := tc.conn
+	tc.connCond.L.Unlock()
+	_ = c.Close()
+}
+
+func resumeConfig(grace time.Duration) *Config {
+	conf := DefaultServerConfig()
+	conf.Resume = &network.ResumeOptions{GracePeriod: grace}
+	return conf
+}
+
+func Test_ResumeServerToClient(t *testing.T) {
+	const total = 200
+	var handled atomic.Int32
+	accepted := make(chan *TcpServerConn, 1)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		handled.Add(1)
+		accepted <- conn.(*TcpServerConn)
+		if _, err := conn.Recv(context.Background()); err != nil {
+			return
+		}
+		for i := 0; i < total; i++ {
+			_ = conn.Send([]byte(fmt.Sprint(i)))
+			time.Sleep(time.Millisecond)
+		}
+		_, _ = conn.Recv(context.Background())
+	}, resumeConfig(time.Second*5))
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	var reconnected atomic.Int32
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM), WithResume(),
+		WithMemLink(&MemLinkOptions{Latency: time.Millisecond * 20}),
+		WithStateHandler(func(state ConnState) {
+			if state == ConnStateReconnected {
+				reconnected.Add(1)
+			}
+		}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	//断线时仍在链路上的消息丢失, 恢复会话后由服务端重放, 每条消息按顺序恰好收到一次
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("start")))
+	sc := <-accepted
+	before := sc.remoteAddr()
+	for i := 0; i < total; i++ {
+		in, err := conn.Recv(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, fmt.Sprint(i), string(in))
+		if i == total/4 {
+			dropClient(conn)
+		}
+	}
+	assert.Equal(t, int32(1), reconnected.Load())
+	assert.Equal(t, int32(1), handled.Load())
+	assert.Equal(t, 1, server.Sessions().CCU())
+
+	//恢复会话后对端地址更新为新连接的地址
+	c, _ := sc.link()
+	assert.NotEqual(t, before, sc.remoteAddr())
+	assert.Equal(t, c.RemoteAddr().String(), sc.remoteAddr())
+}
+
+func Test_ResumeClientToServer(t *testing.T) {
+	const total = 200
+	padding := strings.Repeat("x", 1020)
+	var handled atomic.Int32
+	result := make(chan []string, 1)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		handled.Add(1)
+		var received []string
+		for len(received) < total {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				break
+			}
+			received = append(received, strings.TrimSuffix(string(in), padding))
+			if len(received) == total/4 {
+				//模拟服务端感知到的断线
+				c, _ := conn.(*TcpServerConn).link()
+				_ = c.Close()
+			}
+		}
+		result <- received
+		_, _ = conn.Recv(context.Background())
+	}, resumeConfig(time.Second*5))
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM), WithResume(),
+		WithMemLink(&MemLinkOptions{Latency: time.Millisecond * 20}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	for i := 0; i < total; i++ {
+		assert.NoError(t, conn.Send([]byte(fmt.Sprint(i)+padding)))
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case received := <-result:
+		expected := make([]string, total)
+		for i := range expected {
+			expected[i] = fmt.Sprint(i)
+		}
+		assert.Equal(t, expected, received)
+	case <-time.After(time.Second * 10):
+		t.Fatal("messages not received")
+	}
+	assert.Equal(t, int32(1), handled.Load())
+
+	//服务端每收到 ResumeAckBytes 回复一次确认, 客户端的重放窗口随之清理
+	window := conn.(*TcpClient).resume.window
+	assert.Eventually(t, func() bool {
+		return window.pending() < ResumeAckBytes*2
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func Test_ResumeEncrypted(t *testing.T) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	conf := resumeConfig(time.Second * 5)
+	conf.Encryption = &network.EncryptionOptions{PrivateKey: priv}
+	conf.Handshake = &network.HandshakeOptions{}
+	var handled atomic.Int32
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		handled.Add(1)
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM), WithResume(),
+		WithEncryption(&network.EncryptionOptions{
+			Suite:         network.CipherAES256GCM,
+			PeerPublicKey: priv.PublicKey().Bytes(),
+		}),
+		WithHandshake(&network.HandshakeRequest{Version: 1}))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		msg := fmt.Sprint("hello-", i)
+		assert.NoError(t, conn.Send([]byte(msg)))
+		in, err := conn.Recv(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, msg, string(in))
+		dropClient(conn)
+	}
+	assert.Equal(t, int32(1), handled.Load())
+}
+
+func Test_ResumeExpired(t *testing.T) {
+	result := make(chan error, 1)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		_, err := conn.Recv(context.Background())
+		result <- err
+	}, resumeConfig(time.Millisecond*100))
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	codec := network.NewCodec(MaxIncomingPacket, false, ReadTimeout)
+	raw, err := dialMem(server.Addr(), nil)
+	assert.NoError(t, err)
+	ack, err := sendResume(raw, codec, &network.ResumeRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, ack.Token, resumeTokenSize)
+	_ = raw.Close()
+
+	//超过 GracePeriod 未恢复的会话被关闭
+	select {
+	case err = <-result:
+		assert.ErrorIs(t, err, network.ErrSessionExpired)
+	case <-time.After(time.Second * 5):
+		t.Fatal("session not expired")
+	}
+
+	raw, err = dialMem(server.Addr(), nil)
+	assert.NoError(t, err)
+	defer func() {
+		_ = raw.Close()
+	}()
+	_, err = sendResume(raw, codec, &network.ResumeRequest{Token: ack.Token})
+	assert.ErrorIs(t, err, network.ErrSessionExpired)
+}
+
+func Test_ResumeServerRestart(t *testing.T) {
+	const addr = "resume-restart"
+	echo := func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}
+	server, err := ServeByConfig("mem", addr, echo, resumeConfig(time.Second*5))
+	assert.NoError(t, err)
+
+	conn := DialWithOps(context.Background(), addr, WithProtocol(network.MEM), WithResume())
+	defer func() {
+		_ = conn.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("hello")))
+	_, err = conn.Recv(ctx)
+	assert.NoError(t, err)
+
+	//重启后的服务端没有原来的会话, 客户端不再重连
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer stopCancel()
+	_ = server.GracefulStop(stopCtx)
+	server, err = ServeByConfig("mem", addr, echo, resumeConfig(time.Second*5))
+	assert.NoError(t, err)
+	defer server.Stop()
+	_, err = conn.Recv(ctx)
+	assert.ErrorIs(t, err, network.ErrSessionExpired)
+}
+
+func Test_ResumeClose(t *testing.T) {
+	result := make(chan error, 1)
+	server, err := ServeByConfig("mem", "", func(conn IConn) {
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				result <- err
+				return
+			}
+		}
+	}, resumeConfig(time.Second*10))
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.MEM), WithResume())
+	assert.NoError(t, conn.Send([]byte("hello")))
+	assert.Eventually(t, func() bool {
+		return server.Sessions().CCU() == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	//客户端主动关闭时服务端不等待恢复
+	_ = conn.Close()
+	select {
+	case err = <-result:
+		assert.ErrorIs(t, err, ErrCanceled)
+	case <-time.After(time.Second * 2):
+		t.Fatal("session not closed")
+	}
+}
@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: rtt
+   @2026 10月 周一 10:20
+*/
+
+// 心跳帧(TypeMessageHeartbeat, TypeMessageProbe)的消息体: timestamp<int64> | echo<int64>
+// timestamp 为发送时间, echo 为所回复的对端心跳帧中的 timestamp, 0 表示不是回复, 均为 UnixNano.
+// 收到 echo 不为 0 的心跳帧时, 以当前时间减去 echo 得到一次 RTT 采样; 消息体为空的心跳帧(旧版本对端)不采样
+const heartbeatBodySize = 16
+
+// ConnStats 连接质量统计
+type ConnStats struct {
+	RTT        time.Duration //最近一次 RTT 采样
+	SRTT       time.Duration //平滑 RTT: srtt = 7/8 * srtt + 1/8 * rtt
+	Jitter     time.Duration //相邻两次采样之差的平滑值: jitter += (|d| - jitter) / 16
+	MinRTT     time.Duration
+	Samples    uint64    //采样次数, 为 0 时其它字段无意义
+	LastUpdate time.Time //最近一次采样的时间
+}
+
+// IStats 提供连接质量统计的连接, TcpClient、UdpClient 以及 TCP/KCP/WebSocket 服务端连接实现了该接口,
+// 可以通过 conn.(transport.IStats).Stats() 获取
+type IStats interface {
+	Stats() ConnStats
+}
+
+type rttStats struct {
+	mu    sync.Mutex
+	stats ConnStats
+}
+
+func (s *rttStats) Stats() ConnStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// onEcho 根据对端回复的 echo 采样 RTT, 同时计入 Monitor
+func (s *rttStats) onEcho(echo int64, m *Monitor) {
+	if echo <= 0 {
+		return
+	}
+	rtt := time.Since(time.Unix(0, echo))
+	if rtt < 0 {
+		return
+	}
+	s.observe(rtt)
+	m.ObserveRTT(rtt)
+}
+
+func (s *rttStats) observe(rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &s.stats
+	if st.Samples == 0 {
+		st.SRTT = rtt
+		st.MinRTT = rtt
+	} else {
+		d := rtt - st.RTT
+		if d < 0 {
+			d = -d
+		}
+		st.Jitter += (d - st.Jitter) / 16
+		st.SRTT += (rtt - st.SRTT) / 8
+		if rtt < st.MinRTT {
+			st.MinRTT = rtt
+		}
+	}
+	st.RTT = rtt
+	st.Samples++
+	st.LastUpdate = time.Now()
+}
+
+// encodeHeartbeat 编码携带当前时间的心跳帧, echo 为所回复的对端心跳帧中的 timestamp
+func encodeHeartbeat(codec *mnetwork.Codec, h int8, echo int64) packet2.IPacket {
+	return codec.EncodeBody(heartbeatBody(echo), h)
+}
+
+// heartbeatBody 携带当前时间的心跳帧消息体
+func heartbeatBody(echo int64) []byte {
+	body := make([]byte, heartbeatBodySize)
+	binary.BigEndian.PutUint64(body[:8], uint64(time.Now().UnixNano()))
+	binary.BigEndian.PutUint64(body[8:], uint64(echo))
+	return body
+}
+
+// decodeHeartbeat 解析心跳帧的消息体, 消息体为空时返回 0
+func decodeHeartbeat(body []byte) (timestamp, echo int64) {
+	if len(body) < heartbeatBodySize {
+		return 0, 0
+	}
+	return int64(binary.BigEndian.Uint64(body[:8])), int64(binary.BigEndian.Uint64(body[8:]))
+}
@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: rtt_test
+   @2026 10月 周一 10:50
+*/
+
+func Test_ConnStats(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.HeartbeatTimeout = time.Millisecond * 300
+	accepted := make(chan IConn, 1)
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		accepted <- conn
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr())
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello")
+	remote := (<-accepted).(IStats)
+
+	//服务端空闲探测的回复与其应答为两端各提供 RTT 采样
+	assert.Eventually(t, func() bool {
+		return remote.Stats().Samples >= 2 && conn.(IStats).Stats().Samples >= 2
+	}, time.Second*5, time.Millisecond*20)
+
+	for _, stats := range []ConnStats{remote.Stats(), conn.(IStats).Stats()} {
+		assert.True(t, stats.RTT > 0)
+		assert.True(t, stats.SRTT > 0)
+		assert.True(t, stats.MinRTT <= stats.RTT)
+		assert.False(t, stats.LastUpdate.IsZero())
+	}
+}
+
+func Test_RttStatsObserve(t *testing.T) {
+	var s rttStats
+	s.observe(time.Millisecond * 10)
+	s.observe(time.Millisecond * 20)
+	stats := s.Stats()
+	assert.Equal(t, uint64(2), stats.Samples)
+	assert.Equal(t, time.Millisecond*20, stats.RTT)
+	assert.Equal(t, time.Millisecond*10, stats.MinRTT)
+	assert.Equal(t, time.Microsecond*11250, stats.SRTT)
+	assert.Equal(t, time.Microsecond*625, stats.Jitter)
+
+	//忽略非回复的心跳帧
+	s.onEcho(0, nil)
+	assert.Equal(t, uint64(2), s.Stats().Samples)
+}
@@ -1,8 +1,11 @@
 package transport
 
 import (
-	net "github.com/orbit-w/meteor/modules/net/network"
+	"context"
+	"crypto/tls"
 	"time"
+
+	net "github.com/orbit-w/meteor/modules/net/network"
 )
 
 /*
@@ -18,7 +21,14 @@ type AcceptorOptions struct {
 
 type IServer interface {
 	Stop() error
+	// GracefulStop 用于滚动发布: 停止接收新连接, 各连接发送完已缓存的数据后向对端发送关闭通知,
+	// 阻塞直到所有 _handle 返回; ctx 结束时强制关闭剩余连接并返回 ctx.Err()
+	GracefulStop(ctx context.Context) error
 	Addr() string
+	// Sessions 返回服务端的连接注册表
+	Sessions() *SessionManager
+	// Metrics 返回服务端的传输层指标
+	Metrics() *net.Metrics
 }
 
 func Serve(protocol, host string,
@@ -32,28 +42,113 @@ func ServeByConfig(protocol, host string,
 	parseConfig(&conf)
 	op := conf.ToAcceptorOptions()
 	factory := GetFactory(parseProtocol(protocol))
-	server := factory()
-	if err := server.Serve(host, _handle, op); err != nil {
+	server := &sessionServer{
+		ITransportServer: factory(),
+		sessions:         NewSessionManager(),
+		metrics:          net.NewMetrics(),
+	}
+	server.metrics.SetQueueDepth(server.sessions.queued)
+	op.Metrics = server.metrics
+	if err := server.Serve(host, server.wrapHandle(_handle), op); err != nil {
 		return nil, err
 	}
 
+	server.metricsName = conf.MetricsName
+	if server.metricsName == "" {
+		server.metricsName = protocol + "://" + server.Addr()
+	}
+	net.RegisterMetrics(server.metricsName, server.metrics)
 	return server, nil
 }
 
+// sessionServer 为 ITransportServer 附加连接注册表与指标
+type sessionServer struct {
+	ITransportServer
+	sessions    *SessionManager
+	metrics     *net.Metrics
+	metricsName string
+}
+
+func (s *sessionServer) Sessions() *SessionManager {
+	return s.sessions
+}
+
+func (s *sessionServer) Metrics() *net.Metrics {
+	return s.metrics
+}
+
+func (s *sessionServer) Stop() error {
+	net.UnregisterMetrics(s.metricsName, s.metrics)
+	return s.ITransportServer.Stop()
+}
+
+func (s *sessionServer) GracefulStop(ctx context.Context) error {
+	net.UnregisterMetrics(s.metricsName, s.metrics)
+	return s.ITransportServer.GracefulStop(ctx)
+}
+
+func (s *sessionServer) wrapHandle(_handle func(conn IConn)) func(conn IConn) {
+	return func(conn IConn) {
+		s.sessions.register(conn)
+		defer s.sessions.unregister(conn)
+		_handle(conn)
+	}
+}
+
 type Config struct {
 	MaxIncomingPacket uint32
 	IsGzip            bool
+	Compressor        net.CompressorID //压缩算法, 为 CompressNone 且 IsGzip 为 true 时使用 gzip
+	CompressThreshold int              //压缩阈值, 0 时取 ZMinLen, 小于 0 时压缩所有消息
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
 	Stage             Stage
+	//TLSConfig 不为空时开启 TLS, 需要校验客户端证书(mTLS)时
+	//设置 ClientAuth = tls.RequireAndVerifyClientCert 以及 ClientCAs
+	TLSConfig *tls.Config
+	//HeartbeatTimeout 大于 0 时开启服务端心跳检测: 对端空闲超过 HeartbeatInterval 时发送探测,
+	//超过 HeartbeatTimeout 未收到任何数据帧时关闭连接, Recv 返回 ErrHeartbeatTimeout.
+	//HeartbeatInterval 未设置或者不小于 HeartbeatTimeout 时取 HeartbeatTimeout / 3
+	HeartbeatInterval time.Duration
+	HeartbeatTimeout  time.Duration
+	//Limit 连接级限流与防洪: 最大并发连接数, 单 IP 连接数, 接入速率以及单连接入站速率,
+	//入站速率超限的连接被关闭, Recv 返回 ErrRateLimited
+	Limit net.LimitOptions
+	//Encryption 不为空时开启应用层加密: 客户端连接后先通过 X25519 密钥交换协商会话密钥,
+	//之后的数据帧使用 AES-GCM 或 ChaCha20-Poly1305 加密, 未完成密钥交换的连接被关闭. 不支持 UDP
+	Encryption *net.EncryptionOptions
+	//MetricsName 导出指标时的 server 标签, 默认为 protocol://addr
+	MetricsName string
+	//SendQueue 单连接发送队列的背压: 积压字节数超过 HighWatermark 时按 Policy 拒绝、阻塞、
+	//丢弃最早的消息或者断开慢消费者(Recv 返回 ErrSlowConsumer). 不支持 UDP
+	SendQueue net.SendQueueOptions
+	//Chaos 不为空时为每条连接注入时延、带宽限制、拆分写入、截断以及连接重置等故障, 用于测试. 不支持 UDP
+	Chaos *net.ChaosOptions
+	//Handshake 不为空时开启握手: 客户端连接后(开启加密时在密钥交换之后)发送协议版本、元数据与鉴权令牌,
+	//由 Validate 校验, 校验失败或者超时的连接在 _handle 之前被关闭. 不支持 UDP
+	Handshake *net.HandshakeOptions
+	//Resume 不为空时开启会话恢复: 连接异常断开后会话保留 GracePeriod, 客户端(WithResume)携带会话令牌重连时
+	//在新连接上继续原来的会话并重放对端未收到的消息, _handle 不感知连接的替换. 要求客户端开启 WithResume, 不支持 UDP
+	Resume *net.ResumeOptions
 }
 
 func (c *Config) ToAcceptorOptions() *net.AcceptorOptions {
 	return &net.AcceptorOptions{
 		MaxIncomingPacket: c.MaxIncomingPacket,
 		IsGzip:            c.IsGzip,
+		Compressor:        c.Compressor,
+		CompressThreshold: c.CompressThreshold,
 		ReadTimeout:       c.ReadTimeout,
 		WriteTimeout:      c.WriteTimeout,
+		TLSConfig:         c.TLSConfig,
+		HeartbeatInterval: c.HeartbeatInterval,
+		HeartbeatTimeout:  c.HeartbeatTimeout,
+		Limit:             c.Limit,
+		Encryption:        c.Encryption,
+		SendQueue:         c.SendQueue,
+		Chaos:             c.Chaos,
+		Handshake:         c.Handshake,
+		Resume:            c.Resume,
 	}
 }
 
@@ -94,6 +189,11 @@ func parseConfig(conf **Config) {
 		(*conf).MaxIncomingPacket = MaxIncomingPacket
 	}
 
+	if (*conf).HeartbeatTimeout > 0 &&
+		((*conf).HeartbeatInterval <= 0 || (*conf).HeartbeatInterval >= (*conf).HeartbeatTimeout) {
+		(*conf).HeartbeatInterval = (*conf).HeartbeatTimeout / 3
+	}
+
 	if (*conf).Stage > PROD {
 		(*conf).Stage = DEV
 	}
@@ -107,6 +207,10 @@ func parseProtocol(p string) net.Protocol {
 		return net.UDP
 	case "kcp":
 		return net.KCP
+	case "ws", "websocket":
+		return net.WS
+	case "mem":
+		return net.MEM
 	default:
 		return net.TCP
 	}
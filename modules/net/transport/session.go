@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"sync"
+	"sync/atomic"
+
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+)
+
+/*
+   @Author: orbit-w
+   @File: session
+   @2026 10月 周日 17:40
+*/
+
+// ISession 服务端连接, 由 SessionManager 分配进程内唯一的 ID
+// _handle 中可以通过 conn.(transport.ISession).ID() 获取
+type ISession interface {
+	IConn
+	ID() uint64
+}
+
+// session 服务端连接需要实现的内部接口
+type session interface {
+	ISession
+	setID(id uint64)
+	// sendEncoded 发送已编码的消息 item: size<int32> | data
+	sendEncoded(item []byte) error
+	// pending 发送队列中积压的字节数
+	pending() int
+}
+
+// SessionManager 服务端连接注册表: 为每个连接分配 ID, 统计在线连接数,
+// 支持按 ID 查找连接以及广播/组播
+type SessionManager struct {
+	seq      atomic.Uint64
+	mu       sync.RWMutex
+	sessions map[uint64]session
+}
+
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		sessions: make(map[uint64]session),
+	}
+}
+
+// CCU 当前在线的连接数
+func (sm *SessionManager) CCU() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return len(sm.sessions)
+}
+
+// Get 根据 ID 查找在线连接
+func (sm *SessionManager) Get(id uint64) (ISession, bool) {
+	sm.mu.RLock()
+	sess, ok := sm.sessions[id]
+	sm.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return sess, true
+}
+
+// Range 遍历在线连接, iter 返回 false 时停止遍历; iter 中不能调用 SessionManager 的写方法
+func (sm *SessionManager) Range(iter func(sess ISession) bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, sess := range sm.sessions {
+		if !iter(sess) {
+			return
+		}
+	}
+}
+
+// Broadcast 向所有在线连接发送 data, data 只编码一次后写入各连接的 ControlBuffer
+func (sm *SessionManager) Broadcast(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	item := encodeItem(data)
+	defer packet2.Return(item)
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, sess := range sm.sessions {
+		_ = sess.sendEncoded(item.Data())
+	}
+}
+
+// Multicast 向 ids 对应的在线连接发送 data, 不在线的 ID 被忽略
+func (sm *SessionManager) Multicast(ids []uint64, data []byte) {
+	if len(data) == 0 || len(ids) == 0 {
+		return
+	}
+	item := encodeItem(data)
+	defer packet2.Return(item)
+
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, id := range ids {
+		if sess, ok := sm.sessions[id]; ok {
+			_ = sess.sendEncoded(item.Data())
+		}
+	}
+}
+
+func (sm *SessionManager) register(conn IConn) {
+	sess, ok := conn.(session)
+	if !ok {
+		return
+	}
+	id := sm.seq.Add(1)
+	sess.setID(id)
+	sm.mu.Lock()
+	sm.sessions[id] = sess
+	sm.mu.Unlock()
+}
+
+func (sm *SessionManager) unregister(conn IConn) {
+	sess, ok := conn.(session)
+	if !ok {
+		return
+	}
+	sm.mu.Lock()
+	delete(sm.sessions, sess.ID())
+	sm.mu.Unlock()
+}
+
+func encodeItem(data []byte) packet2.IPacket {
+	w := packet2.WriterP(len(data) + 4)
+	w.WriteBytes32(data)
+	return w
+}
+
+// queued 所有连接发送队列中积压的字节数之和
+func (sm *SessionManager) queued() int {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	var n int
+	for _, sess := range sm.sessions {
+		n += sess.pending()
+	}
+	return n
+}
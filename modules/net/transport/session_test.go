@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: session_test
+   @2026 10月 周日 17:58
+*/
+
+func Test_SessionBroadcast(t *testing.T) {
+	const count = 5
+	ids := make(chan uint64, count)
+	server, err := Serve("tcp", "127.0.0.1:0", func(conn IConn) {
+		ids <- conn.(ISession).ID()
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				return
+			}
+		}
+	})
+	assert.NoError(t, err)
+	defer server.Stop()
+	sessions := server.Sessions()
+
+	conns := make([]IConn, count)
+	for i := range conns {
+		conns[i] = DialWithOps(context.Background(), server.Addr())
+		//首个消息触发服务端的连接建立
+		assert.NoError(t, conns[i].Send([]byte("hello")))
+	}
+	var all []uint64
+	for i := 0; i < count; i++ {
+		id := <-ids
+		_, ok := sessions.Get(id)
+		assert.True(t, ok)
+		all = append(all, id)
+	}
+	assert.Equal(t, count, sessions.CCU())
+
+	sessions.Broadcast([]byte("broadcast"))
+	for _, conn := range conns {
+		assertRecv(t, conn, "broadcast")
+	}
+
+	//组播到除自身外的所有连接, 再广播一条标记消息: 只有组播目标先收到组播消息
+	sessions.Multicast(all[1:], []byte("multicast"))
+	sessions.Broadcast([]byte("mark"))
+	received := 0
+	for _, conn := range conns {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		in, err := conn.Recv(ctx)
+		cancel()
+		assert.NoError(t, err)
+		if string(in) == "multicast" {
+			received++
+			assertRecv(t, conn, "mark")
+		} else {
+			assert.Equal(t, "mark", string(in))
+		}
+	}
+	assert.Equal(t, count-1, received)
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	assert.Eventually(t, func() bool {
+		return sessions.CCU() == 0
+	}, time.Second*5, time.Millisecond*10)
+	_, ok := sessions.Get(all[0])
+	assert.False(t, ok)
+}
+
+func assertRecv(t *testing.T, conn IConn, msg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, string(in))
+}
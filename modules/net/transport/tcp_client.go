@@ -2,6 +2,8 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/binary"
 	"io"
 	"net"
 	"sync"
@@ -27,22 +29,35 @@ import (
 type TcpClient struct {
 	state            atomic.Uint32
 	lastAck          atomic.Int64
+	slow             atomic.Bool //发送队列积压超过高水位被断开
 	maxIncomingSize  uint32
+	protocol         mnetwork.Protocol
+	tlsConfig        *tls.Config
+	encryption       *mnetwork.EncryptionOptions
+	memLink          *MemLinkOptions
+	chaos            *mnetwork.ChaosOptions
+	handshake        *mnetwork.HandshakeRequest
+	resume           *resumeState //开启会话恢复时不为空
+	host             string       //拨号地址, 断线重连时使用
 	remoteAddr       string
 	localAddr        string
 	ctx              context.Context
 	cancel           context.CancelFunc
 	codec            *mnetwork.Codec
 	conn             net.Conn
+	wmu              sync.Mutex //串行化帧的编码与写出, 开启加密时 AEAD 序号需要与帧写出连接的顺序一致
 	buf              *ControlBuffer
 	sw               *sender_wrapper.SenderWrapper
 	r                *mnetwork.BlockReceiver
 	unregisterHandle func()
+	stateHandler     func(state ConnState)
+	reconnect        bool
 	writeTimeout     time.Duration
 	m                *Monitor
 
 	connState int8       //代表链接状态
 	connCond  *sync.Cond //链接状态条件变量
+	rtt       rttStats
 	logger    *mlog.Logger
 }
 
@@ -54,17 +69,31 @@ func DialWithOps(ctx context.Context, remoteAddr string, _ops ...Opt) IConn {
 
 func DialContext(ctx context.Context, remoteAddr string, dp *DialOption, _ops ...Opt) IConn {
 	parseOptions(dp, _ops...)
+	if dp.Protocol == mnetwork.UDP {
+		return dialUdpContext(ctx, remoteAddr, dp)
+	}
+
 	_ctx, cancel := context.WithCancel(ctx)
 	buf := new(ControlBuffer)
 	BuildControlBuffer(buf, dp.MaxIncomingPacket)
+	buf.SetMaxPending(dp.MaxPendingBytes)
 	tc := &TcpClient{
+		protocol:         dp.Protocol,
+		tlsConfig:        dp.TLSConfig,
+		encryption:       dp.Encryption,
+		memLink:          dp.MemLink,
+		chaos:            dp.Chaos,
+		handshake:        dp.Handshake,
+		host:             remoteAddr,
 		remoteAddr:       remoteAddr,
 		unregisterHandle: dp.DisconnectHandler,
+		stateHandler:     dp.StateHandler,
+		reconnect:        dp.Reconnect,
 		maxIncomingSize:  dp.MaxIncomingPacket,
 		buf:              buf,
 		ctx:              _ctx,
 		cancel:           cancel,
-		codec:            mnetwork.NewCodec(dp.MaxIncomingPacket, dp.IsGzip, dp.ReadTimeout),
+		codec:            dp.newCodec(),
 		r:                mnetwork.NewBlockReceiver(),
 		writeTimeout:     dp.WriteTimeout,
 		connCond:         sync.NewCond(&sync.Mutex{}),
@@ -72,14 +101,18 @@ func DialContext(ctx context.Context, remoteAddr string, dp *DialOption, _ops ..
 		logger:           newTcpClientPrefixLogger(),
 	}
 
-	if dp.NeedToMonitor {
-		tc.m = NewMonitor()
+	if dp.Resume {
+		tc.resume = &resumeState{window: newReplayWindow(ResumeWindowSize)}
 	}
+	tc.m = newMonitor(dp.NeedToMonitor, clientMetrics)
+	buf.SetSendQueue(dp.SendQueue, tc.disconnectSlow)
 
+	go tc.handleDial(dp)
+	//阻塞模式下等待首次拨号结束(成功或达到重试上限)
 	if dp.IsBlock {
-		tc.handleDial(dp)
-	} else {
-		go tc.handleDial(dp)
+		tc.connCond.L.Lock()
+		tc.waitDial()
+		tc.connCond.L.Unlock()
 	}
 
 	return tc
@@ -94,25 +127,68 @@ func (tc *TcpClient) Send(out []byte) error {
 	return err
 }
 
+// SendContext 与 Send 相同, 发送队列策略为 QueuePolicyBlock 时阻塞等待可以通过 ctx 取消
+func (tc *TcpClient) SendContext(ctx context.Context, out []byte) error {
+	if len(out) == 0 {
+		return nil
+	}
+	err := tc.buf.SetContext(ctx, out)
+	if err == nil {
+		tc.m.IncrementOutboundTraffic(uint64(len(out)))
+	}
+	return err
+}
+
+// SendWithPriority 与 Send 相同, out 写入 p 对应的发送通道, PriorityUrgent 的消息优先发送
+func (tc *TcpClient) SendWithPriority(out []byte, p Priority) error {
+	if len(out) == 0 {
+		return nil
+	}
+	err := tc.buf.SetWithPriority(out, p)
+	if err == nil {
+		tc.m.IncrementOutboundTraffic(uint64(len(out)))
+	}
+	return err
+}
+
+// QueuedBytes 当前积压的发送字节数, 包括断线期间缓存的数据
+func (tc *TcpClient) QueuedBytes() int {
+	return tc.buf.Pending()
+}
+
 func (tc *TcpClient) Recv(ctx context.Context) ([]byte, error) {
 	return tc.r.Recv(ctx)
 }
 
+// RecvMessage 与 Recv 相同, 返回的消息引用池化的缓冲区, 使用完毕后需要调用 Message.Release
+func (tc *TcpClient) RecvMessage(ctx context.Context) (mnetwork.Message, error) {
+	return tc.r.RecvMessage(ctx)
+}
+
+// Close 关闭连接, 开启会话恢复时先通知服务端结束会话
 func (tc *TcpClient) Close() error {
 	if tc.state.CompareAndSwap(cliStateNormal, cliStateStopped) {
 		tc.connCond.L.Lock()
-		for !(tc.connState == connected || tc.connState == connectedFailed) {
-			tc.connCond.Wait()
-		}
-		tc.connCond.L.Unlock()
-
+		tc.waitDial()
 		if tc.conn != nil {
+			if tc.resume != nil {
+				_ = tc.sendFrame(tc.conn, nil, mnetwork.TypeMessageClose)
+			}
 			_ = tc.conn.Close()
 		}
+		tc.connCond.L.Unlock()
+		tc.cancel()
 	}
 	return nil
 }
 
+// waitDial 等待首次拨号结束, 调用方需持有 connCond.L
+func (tc *TcpClient) waitDial() {
+	for !(tc.connState == connected || tc.connState == connectedFailed) {
+		tc.connCond.Wait()
+	}
+}
+
 func (tc *TcpClient) handleDial(_ *DialOption) {
 	defer func() {
 		if tc.unregisterHandle != nil {
@@ -125,6 +201,7 @@ func (tc *TcpClient) handleDial(_ *DialOption) {
 		return tc.dial()
 	}
 
+	tc.notifyState(ConnStateConnecting)
 	//When the number of failed connection attempts reaches the upper limit,
 	//the conn state will be set to the 'disconnected' state,
 	//and all virtual streams will be closed.
@@ -135,6 +212,7 @@ func (tc *TcpClient) handleDial(_ *DialOption) {
 		tc.connCond.L.Unlock()
 		tc.connCond.Broadcast()
 		tc.r.OnClose(err)
+		tc.notifyState(ConnStateDisconnected)
 		return
 	}
 
@@ -142,29 +220,99 @@ func (tc *TcpClient) handleDial(_ *DialOption) {
 	tc.connState = connected
 	tc.connCond.L.Unlock()
 	tc.connCond.Broadcast()
+	tc.notifyState(ConnStateConnected)
 
-	tc.sw = sender_wrapper.NewSender(tc.SendData)
+	for {
+		err := tc.serve()
+		tc.notifyState(ConnStateDisconnected)
+		if !tc.reconnect || tc.state.Load() == cliStateStopped {
+			tc.r.OnClose(err)
+			tc.cancel()
+			return
+		}
+
+		tc.logger.Info("Disconnected, try to reconnect", zap.String("RemoteAddr", tc.host), zap.Error(err))
+		tc.notifyState(ConnStateConnecting)
+		if err = tc.redial(); err != nil {
+			tc.r.OnClose(err)
+			tc.cancel()
+			return
+		}
+		tc.notifyState(ConnStateReconnected)
+	}
+}
+
+// serve 在当前连接上运行读写循环, 阻塞直到连接断开, 返回断开原因.
+// 返回前会暂停 ControlBuffer, 未发送的数据保留到下一条连接
+func (tc *TcpClient) serve() error {
+	conn := tc.conn
+	vw := newVectorWriter(tc.codec)
+	tc.sw = sender_wrapper.NewBatchSender(func(packs []packet2.IPacket) error {
+		return tc.sendBatch(conn, vw, packs)
+	}, MaxCoalesce)
 	tc.buf.Run(tc.sw)
-	tc.remoteAddr = tc.conn.RemoteAddr().String()
-	tc.localAddr = tc.conn.LocalAddr().String()
-	go tc.keepalive()
-	<-tc.ctx.Done()
+	tc.remoteAddr = conn.RemoteAddr().String()
+	tc.localAddr = conn.LocalAddr().String()
+
+	tc.m.onOpen()
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		tc.keepalive(conn, done)
+		close(exited)
+	}()
+	err := tc.reader(conn)
+	close(done)
+	if tc.slow.CompareAndSwap(true, false) {
+		err = ErrSlowConsumer
+	}
+	tc.m.onClose(err)
+	tc.buf.Pause()
+	if tc.encryption != nil || tc.resume != nil {
+		//重连后会为 codec 设置新的 Cipher, 恢复会话时需要完整的重放窗口, 需要等待旧连接的发送协程与心跳协程退出
+		<-tc.sw.Done()
+		<-exited
+	}
+	return err
+}
+
+// disconnectSlow 发送队列积压超过高水位时断开当前连接, 开启重连时未发送的数据保留到下一条连接
+func (tc *TcpClient) disconnectSlow() {
+	tc.connCond.L.Lock()
+	conn := tc.conn
+	tc.connCond.L.Unlock()
+	if conn != nil {
+		tc.logger.Error("Slow consumer", zap.String("RemoteAddr", tc.remoteAddr), zap.Int("Queued", tc.buf.Pending()))
+		tc.slow.Store(true)
+		_ = conn.Close()
+	}
 }
 
 func (tc *TcpClient) SendData(pack packet2.IPacket) error {
+	tc.connCond.L.Lock()
+	conn := tc.conn
+	tc.connCond.L.Unlock()
+	return tc.sendPack(conn, pack)
+}
+
+// sendPack implicitly call pack.Return
+func (tc *TcpClient) sendPack(conn net.Conn, pack packet2.IPacket) error {
 	defer packet2.Return(pack)
+	tc.wmu.Lock()
+	defer tc.wmu.Unlock()
 	body, err := tc.codec.Encode(pack.Data(), mnetwork.TypeMessageRaw)
 	if err != nil {
 		return err
 	}
 
 	defer packet2.Return(body)
+	if tc.resume != nil {
+		tc.resume.window.push(pack.Data())
+	}
 	data := body.Data()
-	err = tc.sendData(data)
+	err = tc.sendData(conn, data)
 	if err != nil {
-		if tc.conn != nil {
-			_ = tc.conn.Close()
-		}
+		_ = conn.Close()
 		tc.logger.Error("Send data failed", zap.Error(err))
 		return err
 	}
@@ -172,61 +320,191 @@ func (tc *TcpClient) SendData(pack packet2.IPacket) error {
 	return nil
 }
 
-func (tc *TcpClient) sendData(data []byte) error {
-	if err := tc.conn.SetWriteDeadline(time.Now().Add(tc.writeTimeout)); err != nil {
+// sendBatch 将发送协程中积压的多个批次通过一次 writev 写出, implicitly call packet.Return.
+// 开启会话恢复时批次在写出之前保存到重放窗口
+func (tc *TcpClient) sendBatch(conn net.Conn, vw *vectorWriter, packs []packet2.IPacket) error {
+	var size int
+	for _, pack := range packs {
+		size += pack.Len()
+	}
+	defer func() {
+		for _, pack := range packs {
+			packet2.Return(pack)
+		}
+		tc.buf.OnSent(size)
+	}()
+
+	tc.wmu.Lock()
+	defer tc.wmu.Unlock()
+	if tc.resume != nil {
+		for _, pack := range packs {
+			tc.resume.window.push(pack.Data())
+		}
+	}
+	n, err := vw.write(conn, packs, tc.writeTimeout)
+	if err != nil {
+		_ = conn.Close()
+		tc.logger.Error("Send data failed", zap.Error(err))
+		return err
+	}
+	tc.m.IncrementRealOutboundTraffic(uint64(n))
+	return nil
+}
+
+// sendFrame 绕过发送协程向 conn 写出一帧控制消息, 与发送协程互斥
+func (tc *TcpClient) sendFrame(conn net.Conn, body []byte, h int8) error {
+	tc.wmu.Lock()
+	defer tc.wmu.Unlock()
+	pack := tc.codec.EncodeBody(body, h)
+	defer packet2.Return(pack)
+	return tc.sendData(conn, pack.Data())
+}
+
+func (tc *TcpClient) sendData(conn net.Conn, data []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(tc.writeTimeout)); err != nil {
 		return err
 	}
-	_, err := tc.conn.Write(data)
+	_, err := conn.Write(data)
 	return err
 }
 
 func (tc *TcpClient) dial() error {
-	conn, err := net.Dial("tcp", tc.remoteAddr)
+	conn, err := tc.dialConn()
 	if err != nil {
 		return err
 	}
 
+	tc.connCond.L.Lock()
 	tc.conn = conn
-	go tc.reader()
+	tc.connCond.L.Unlock()
 	return nil
 }
 
-func (tc *TcpClient) reader() {
+// redial 断线后以指数退避持续重连, 直到连接成功或客户端被关闭
+func (tc *TcpClient) redial() error {
+	var retried int
+	for {
+		conn, err := tc.dialConn()
+		if err == nil {
+			tc.connCond.L.Lock()
+			if tc.state.Load() == cliStateStopped {
+				tc.connCond.L.Unlock()
+				_ = conn.Close()
+				return ErrCanceled
+			}
+			tc.conn = conn
+			tc.connCond.L.Unlock()
+			return nil
+		}
+		if isFatalDialErr(err) {
+			return err
+		}
+
+		backoff := time.Millisecond * time.Duration(100<<number_utils.Min[int](retried, MaxRetried))
+		retried++
+		select {
+		case <-time.After(backoff):
+		case <-tc.ctx.Done():
+			return ErrCanceled
+		}
+	}
+}
+
+// dialConn 拨号, 配置了故障注入时包装连接, 并在开启加密时完成密钥交换, 开启握手时完成握手,
+// 开启会话恢复时建立或者恢复会话
+func (tc *TcpClient) dialConn() (net.Conn, error) {
+	conn, err := dialConn(tc.protocol, tc.host, tc.tlsConfig, tc.memLink)
+	if err != nil {
+		return nil, err
+	}
+	if tc.chaos != nil {
+		conn = mnetwork.WrapChaos(conn, tc.chaos)
+	}
+	if tc.encryption != nil {
+		if err = clientKeyExchange(conn, tc.codec, tc.encryption); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if tc.handshake != nil {
+		if err = sendHandshake(conn, tc.codec, tc.handshake); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if tc.resume != nil {
+		if err = tc.resumeSession(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// resumeSession 首次连接时取得会话令牌, 重连时携带令牌恢复会话并重放服务端未收到的消息
+func (tc *TcpClient) resumeSession(conn net.Conn) error {
+	rs := tc.resume
+	ack, err := sendResume(conn, tc.codec, &mnetwork.ResumeRequest{Token: rs.token, RecvSeq: rs.recvSeq})
+	if err != nil {
+		return err
+	}
+	rs.token = ack.Token
+	rs.window.ack(ack.RecvSeq)
+	batches, err := rs.window.since(ack.RecvSeq)
+	if err != nil {
+		return err
+	}
+	return replay(conn, tc.codec, batches)
+}
+
+func dialConn(p mnetwork.Protocol, remoteAddr string, tlsConf *tls.Config, link *MemLinkOptions) (net.Conn, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch p {
+	case mnetwork.KCP:
+		conn, err = dialKcp(remoteAddr)
+	case mnetwork.MEM:
+		conn, err = dialMem(remoteAddr, link)
+	case mnetwork.WS:
+		//TLS 由 websocket 握手完成
+		return dialWebSocket(remoteAddr, tlsConf)
+	default:
+		conn, err = net.Dial("tcp", remoteAddr)
+	}
+	if err != nil || tlsConf == nil {
+		return conn, err
+	}
+	return clientHandshake(conn, remoteAddr, tlsConf)
+}
+
+// reader 阻塞读取 conn 直到出错, 返回连接断开的原因
+func (tc *TcpClient) reader(conn net.Conn) (cErr error) {
 	header := make([]byte, HeadLen)
-	body := make([]byte, tc.maxIncomingSize)
 
 	var (
-		in    []byte
-		err   error
-		bytes []byte
-		head  int8
+		in   []byte
+		buf  *packet2.Buffer
+		err  error
+		head int8
 	)
 
 	defer utils.RecoverPanic()
 	defer func() {
-		if tc.conn != nil {
-			_ = tc.conn.Close()
-		}
+		_ = conn.Close()
 
-		if err != nil {
-			if err == io.EOF || IsClosedConnError(err) {
-				tc.r.OnClose(ErrCanceled)
-			} else {
-				tc.r.OnClose(err)
-			}
+		if err != nil && err != io.EOF && !IsClosedConnError(err) {
+			cErr = err
 		} else {
-			tc.r.OnClose(ErrCanceled)
-		}
-
-		if tc.cancel != nil {
-			tc.cancel()
+			cErr = ErrCanceled
 		}
 	}()
 
 	tc.ack()
 
 	for {
-		in, head, err = tc.codec.BlockDecodeBody(tc.conn, header, body)
+		in, buf, head, err = tc.codec.BlockDecodeBuffer(conn, header)
 		if err != nil {
 			return
 		}
@@ -235,33 +513,65 @@ func (tc *TcpClient) reader() {
 
 		switch head {
 		case mnetwork.TypeMessageHeartbeat:
+			_, echo := decodeHeartbeat(in)
+			tc.rtt.onEcho(echo, tc.m)
 			tc.heartbeat()
+		case mnetwork.TypeMessageClose:
+			buf.Release()
+			err = ErrServerShutdown
+			return
+		case mnetwork.TypeMessageProbe:
+			timestamp, _ := decodeHeartbeat(in)
+			tc.replyProbe(conn, timestamp)
+		case mnetwork.TypeMessageSeqAck:
+			if tc.resume != nil {
+				tc.resume.onSeqAck(in)
+			}
 		default:
-			if len(in) > 0 {
-				r := packet2.ReaderP(in)
-				for len(r.Remain()) > 0 {
-					bytes, err = r.ReadBytes32()
-					if err != nil {
-						break
-					}
-					tc.dispatch(bytes)
+			tc.m.IncrementRealInboundTraffic(uint64(HeadLen) + uint64(binary.BigEndian.Uint32(header)))
+			for len(in) > 0 {
+				item, remain, ok := nextItem(in)
+				if !ok {
+					break
+				}
+				in = remain
+				tc.m.IncrementInboundTraffic(uint64(len(item)))
+				if tc.resume != nil {
+					tc.resume.onRecv(len(item))
 				}
+				tc.dispatch(item, buf)
+			}
+			if tc.resume != nil && tc.resume.ackDue() {
+				tc.sendSeqAck(conn)
 			}
 		}
+		buf.Release()
 	}
 }
 
-func (tc *TcpClient) dispatch(bytes []byte) {
-	if bytes != nil && len(bytes) != 0 {
-		tc.r.Put(bytes, nil)
+// replyProbe 回复服务端的心跳探测, 回显探测帧的 timestamp 供服务端计算 RTT
+func (tc *TcpClient) replyProbe(conn net.Conn, timestamp int64) {
+	if err := tc.sendFrame(conn, heartbeatBody(timestamp), mnetwork.TypeMessageHeartbeat); err != nil {
+		tc.logger.Error("Reply probe failed", zap.Error(err))
 	}
 }
 
-func (tc *TcpClient) keepalive() {
-	codec := mnetwork.NewCodec(MaxIncomingPacket, false, 0)
-	ping, _ := codec.Encode(nil, mnetwork.TypeMessageHeartbeat)
-	defer packet2.Return(ping)
+// sendSeqAck 向服务端确认已收到的消息序号
+func (tc *TcpClient) sendSeqAck(conn net.Conn) {
+	if err := tc.sendFrame(conn, mnetwork.EncodeSeqAck(tc.resume.recvSeq), mnetwork.TypeMessageSeqAck); err != nil {
+		tc.logger.Error("Send seq ack failed", zap.Error(err))
+	}
+}
 
+// dispatch 投递一条消息, 消息直接引用 buf 的内存并持有 buf 的一个引用
+func (tc *TcpClient) dispatch(bytes []byte, buf *packet2.Buffer) {
+	if len(bytes) != 0 {
+		buf.Retain()
+		tc.r.PutMessage(mnetwork.NewMessage(bytes, buf))
+	}
+}
+
+func (tc *TcpClient) keepalive(conn net.Conn, done <-chan struct{}) {
 	prev := time.Now().UnixNano()
 	timeoutLeft := time.Duration(0)
 	outstandingPing := false
@@ -280,24 +590,35 @@ func (tc *TcpClient) keepalive() {
 
 			if outstandingPing && timeoutLeft <= 0 {
 				tc.logger.Error("No heartbeat", zap.String("RemoteAddr", tc.remoteAddr))
-				_ = tc.conn.Close()
+				_ = conn.Close()
 				return
 			}
 
 			if !outstandingPing {
-				_ = tc.sendData(ping.Data())
+				_ = tc.sendFrame(conn, heartbeatBody(0), mnetwork.TypeMessageHeartbeat)
 				timeoutLeft = PingTimeOut
 				outstandingPing = true
 			}
 			sd := number_utils.Min[time.Duration](AckInterval, timeoutLeft)
 			timeoutLeft -= sd
 			timer.Reset(sd)
-		case <-tc.ctx.Done():
+		case <-done:
 			return
 		}
 	}
 }
 
+// Stats 连接质量统计, 断线重连后保留之前的采样
+func (tc *TcpClient) Stats() ConnStats {
+	return tc.rtt.Stats()
+}
+
+func (tc *TcpClient) notifyState(state ConnState) {
+	if tc.stateHandler != nil {
+		tc.stateHandler(state)
+	}
+}
+
 func (tc *TcpClient) ack() {
 	tc.lastAck.Store(time.Now().UnixNano())
 }
@@ -323,6 +644,9 @@ func withRetry(handle func() error) error {
 		if err == nil {
 			return nil
 		}
+		if isFatalDialErr(err) {
+			return err
+		}
 		//exponential backoff
 		time.Sleep(time.Millisecond * time.Duration(100<<retried))
 		if retried >= MaxRetried {
@@ -349,6 +673,10 @@ func parseOptions(dp *DialOption, ops ...Opt) {
 	if dp.ReadTimeout <= 0 {
 		dp.ReadTimeout = ReadTimeout
 	}
+
+	if dp.Protocol == "" {
+		dp.Protocol = mnetwork.TCP
+	}
 	return
 }
 
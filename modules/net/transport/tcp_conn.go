@@ -2,8 +2,12 @@ package transport
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"io"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/orbit-w/meteor/bases/misc/utils"
@@ -21,7 +25,10 @@ import (
 */
 
 type TcpServerConn struct {
-	authed bool
+	authed bool //完成握手, 未开启握手时为 false
+	id     uint64
+	mu     sync.Mutex //保护 addr, conn, codec 与 sw, 恢复会话时由读协程替换
+	wmu    sync.Mutex //串行化帧的编码与写出, 开启加密时 AEAD 序号需要与帧写出连接的顺序一致
 	addr   string
 	conn   net.Conn
 	codec  *mnetwork.Codec
@@ -32,36 +39,147 @@ type TcpServerConn struct {
 	r      *mnetwork.BlockReceiver
 	logger *mlog.Logger
 	m      *Monitor
+	in     *mnetwork.InboundLimiter
 
+	stopDrain    func() bool //取消服务端停止时的 drain 回调
 	writeTimeout time.Duration
+
+	lastActive atomic.Int64          //最近一次收到对端数据帧的时间
+	closeErr   atomic.Pointer[error] //服务端主动关闭连接的原因, 通过 Recv 返回
+	done       chan struct{}         //HandleLoop 退出时关闭
+	rtt        rttStats
+	handshake  *mnetwork.HandshakeRequest //客户端的握手信息
+	resume     *serverResume              //开启会话恢复时不为空
+}
+
+// acceptedConn 连接建立阶段协商的结果
+type acceptedConn struct {
+	codec     *mnetwork.Codec
+	handshake *mnetwork.HandshakeRequest
+	resume    *mnetwork.ResumeRequest
 }
 
 func NewTcpServerConn(ctx context.Context, _conn net.Conn, maxIncomingPacket uint32, head, body []byte,
 	readTO, writeTO time.Duration, isGzip, needToMonitor bool) IConn {
+	op := &mnetwork.AcceptorOptions{
+		MaxIncomingPacket: maxIncomingPacket,
+		IsGzip:            isGzip,
+		NeedToMonitor:     needToMonitor,
+		ReadTimeout:       readTO,
+		WriteTimeout:      writeTO,
+	}
+	ts, _ := newTcpServerConn(ctx, _conn, &acceptedConn{codec: op.NewCodec()}, head, body, op)
+	return ts
+}
+
+// serveConn 在当前协程中建立服务端连接并运行 _handle, 连接建立阶段的握手失败时直接关闭连接;
+// 客户端恢复会话时连接交给原来的会话, 不再调用 _handle
+func serveConn(ctx context.Context, _conn net.Conn, head, body []byte, op *mnetwork.AcceptorOptions,
+	_handle func(conn IConn)) {
+	if op.Chaos != nil {
+		_conn = mnetwork.WrapChaos(_conn, op.Chaos)
+	}
+	ac, err := acceptConn(ctx, _conn, head, body, op)
+	if err != nil {
+		newTcpServerConnPrefixLogger().Error("Handshake failed", zap.String("Addr", _conn.RemoteAddr().String()), zap.Error(err))
+		_ = _conn.Close()
+		return
+	}
+	if ac.resume != nil && len(ac.resume.Token) > 0 {
+		if err = resumeSession(_conn, ac, op); err != nil {
+			newTcpServerConnPrefixLogger().Error("Resume failed", zap.String("Addr", _conn.RemoteAddr().String()), zap.Error(err))
+		}
+		_ = _conn.Close()
+		return
+	}
+
+	conn, err := newTcpServerConn(ctx, _conn, ac, head, body, op)
+	if err != nil {
+		newTcpServerConnPrefixLogger().Error("Handshake failed", zap.String("Addr", _conn.RemoteAddr().String()), zap.Error(err))
+		_ = _conn.Close()
+		return
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_handle(conn)
+}
+
+// acceptConn 连接建立阶段: 开启加密时完成密钥交换, 开启握手时完成握手与鉴权, 开启会话恢复时读取会话恢复请求
+func acceptConn(ctx context.Context, _conn net.Conn, head, body []byte, op *mnetwork.AcceptorOptions) (*acceptedConn, error) {
+	ac := &acceptedConn{codec: op.NewCodec()}
+	if op.Encryption != nil {
+		if err := serverKeyExchange(_conn, ac.codec, head, body, op.Encryption); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if op.Handshake != nil {
+		if ac.handshake, err = acceptHandshake(ctx, _conn, ac.codec, head, body, op.Handshake); err != nil {
+			return nil, err
+		}
+	}
+	if op.Resume != nil {
+		if ac.resume, err = readResume(ctx, _conn, ac.codec, head, body); err != nil {
+			return nil, err
+		}
+	}
+	return ac, nil
+}
+
+// newTcpServerConn 使用 acceptConn 协商的结果建立服务端连接, 开启会话恢复时分配会话令牌并回复客户端
+func newTcpServerConn(ctx context.Context, _conn net.Conn, ac *acceptedConn, head, body []byte, op *mnetwork.AcceptorOptions) (*TcpServerConn, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 	cCtx, cancel := context.WithCancel(ctx)
 	ts := &TcpServerConn{
+		authed:       ac.handshake != nil,
+		handshake:    ac.handshake,
 		conn:         _conn,
 		addr:         _conn.RemoteAddr().String(),
-		codec:        mnetwork.NewCodec(maxIncomingPacket, isGzip, readTO),
+		codec:        ac.codec,
 		ctx:          cCtx,
 		cancel:       cancel,
 		r:            mnetwork.NewBlockReceiver(),
-		writeTimeout: writeTO,
+		writeTimeout: op.WriteTimeout,
 		logger:       newTcpServerConnPrefixLogger(),
+		done:         make(chan struct{}),
+		in:           mnetwork.NewInboundLimiter(&op.Limit),
 	}
-
-	sw := sender_wrapper.NewSender(ts.SendData)
-	ts.sw = sw
-	ts.buf = NewControlBuffer(maxIncomingPacket, ts.sw)
-	if needToMonitor {
-		ts.m = NewMonitor()
+	if op.Resume != nil {
+		ts.resume = newServerResume(op)
+		ack := mnetwork.EncodeResumeAck(&mnetwork.ResumeAck{Token: ts.resume.token}, nil)
+		if err := writeFrame(_conn, ts.codec, ack, mnetwork.TypeMessageResumeAck); err != nil {
+			cancel()
+			return nil, err
+		}
 	}
 
+	ts.sw = ts.newSender(_conn, ts.codec)
+	ts.buf = NewControlBuffer(op.MaxIncomingPacket, ts.sw)
+	ts.buf.SetSendQueue(op.SendQueue, func() {
+		ts.logger.Error("Slow consumer", zap.String("Addr", ts.remoteAddr()), zap.Int("Queued", ts.buf.Pending()))
+		ts.closeWithErr(ErrSlowConsumer)
+	})
+	ts.m = newMonitor(op.NeedToMonitor, op.Metrics)
+	ts.m.onOpen()
+	//服务端 GracefulStop 时通知对端关闭, Stop 时不通知
+	ts.stopDrain = context.AfterFunc(ctx, func() {
+		if mnetwork.IsDraining(ctx) {
+			ts.drain()
+		}
+	})
+
+	ts.active()
+	if ts.resume != nil {
+		resumeSessions.add(ts)
+	}
 	go ts.HandleLoop(head, body)
-	return ts
+	if op.HeartbeatTimeout > 0 {
+		go ts.keepalive(op.HeartbeatInterval, op.HeartbeatTimeout)
+	}
+	return ts, nil
 }
 
 func (ts *TcpServerConn) Send(data []byte) (err error) {
@@ -69,64 +187,211 @@ func (ts *TcpServerConn) Send(data []byte) (err error) {
 		return nil
 	}
 
-	err = ts.buf.Set(data)
+	if err = ts.buf.Set(data); err == nil {
+		ts.m.IncrementOutboundTraffic(uint64(len(data)))
+	}
+	return
+}
+
+// SendContext 与 Send 相同, 发送队列策略为 QueuePolicyBlock 时阻塞等待可以通过 ctx 取消
+func (ts *TcpServerConn) SendContext(ctx context.Context, data []byte) (err error) {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err = ts.buf.SetContext(ctx, data); err == nil {
+		ts.m.IncrementOutboundTraffic(uint64(len(data)))
+	}
+	return
+}
+
+// SendWithPriority 与 Send 相同, data 写入 p 对应的发送通道, PriorityUrgent 的消息优先发送
+func (ts *TcpServerConn) SendWithPriority(data []byte, p Priority) (err error) {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err = ts.buf.SetWithPriority(data, p); err == nil {
+		ts.m.IncrementOutboundTraffic(uint64(len(data)))
+	}
 	return
 }
 
+// QueuedBytes 当前积压的发送字节数
+func (ts *TcpServerConn) QueuedBytes() int {
+	return ts.buf.Pending()
+}
+
 func (ts *TcpServerConn) Recv(ctx context.Context) ([]byte, error) {
 	return ts.r.Recv(ctx)
 }
 
+// ID 由 SessionManager 分配的连接 ID
+func (ts *TcpServerConn) ID() uint64 {
+	return ts.id
+}
+
+func (ts *TcpServerConn) setID(id uint64) {
+	ts.id = id
+}
+
+func (ts *TcpServerConn) sendEncoded(item []byte) error {
+	err := ts.buf.SetEncoded(item)
+	if err == nil {
+		ts.m.IncrementOutboundTraffic(uint64(len(item) - 4))
+	}
+	return err
+}
+
+func (ts *TcpServerConn) pending() int {
+	return ts.buf.Pending()
+}
+
+// Handshake 客户端的握手信息, 未开启握手时返回 nil
+func (ts *TcpServerConn) Handshake() *mnetwork.HandshakeRequest {
+	if !ts.authed {
+		return nil
+	}
+	return ts.handshake
+}
+
+// Stats 连接质量统计, 只有开启服务端心跳检测(Config.HeartbeatTimeout)时才有采样
+func (ts *TcpServerConn) Stats() ConnStats {
+	return ts.rtt.Stats()
+}
+
+// Close 关闭连接, 开启会话恢复时会话随之结束, 不再等待客户端恢复
 func (ts *TcpServerConn) Close() error {
-	return ts.conn.Close()
+	if ts.resume != nil {
+		ts.resume.close()
+	}
+	conn, _ := ts.link()
+	return conn.Close()
+}
+
+// remoteAddr 当前连接的对端地址, 恢复会话时被替换
+func (ts *TcpServerConn) remoteAddr() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.addr
+}
+
+// link 当前的连接与 codec, 恢复会话时二者被替换
+func (ts *TcpServerConn) link() (net.Conn, *mnetwork.Codec) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.conn, ts.codec
 }
 
 // SendData implicitly call body.Return
 // coding: size<int32> | gzipped<bool> | body<bytes>
 func (ts *TcpServerConn) SendData(out packet2.IPacket) error {
 	defer packet2.Return(out)
-	pack, err := ts.codec.Encode(out.Data(), mnetwork.TypeMessageRaw)
+	ts.wmu.Lock()
+	defer ts.wmu.Unlock()
+	conn, codec := ts.link()
+	pack, err := codec.Encode(out.Data(), mnetwork.TypeMessageRaw)
 	if err != nil {
 		return err
 	}
 	defer packet2.Return(pack)
 
-	if err = ts.sendData(pack.Data()); err != nil {
-		if ts.conn != nil {
-			_ = ts.conn.Close()
+	if ts.resume != nil {
+		ts.resume.window.push(out.Data())
+	}
+	if err = ts.write(conn, pack.Data()); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	ts.m.IncrementRealOutboundTraffic(uint64(pack.Len()))
+	return nil
+}
+
+// newSender 创建向 conn 写出的发送协程
+func (ts *TcpServerConn) newSender(conn net.Conn, codec *mnetwork.Codec) *sender_wrapper.SenderWrapper {
+	vw := newVectorWriter(codec)
+	return sender_wrapper.NewBatchSender(func(outs []packet2.IPacket) error {
+		return ts.sendBatch(conn, vw, outs)
+	}, MaxCoalesce)
+}
+
+// sendBatch 将发送协程中积压的多个批次通过一次 writev 写出, implicitly call packet.Return.
+// 开启会话恢复时批次在写出之前保存到重放窗口
+func (ts *TcpServerConn) sendBatch(conn net.Conn, vw *vectorWriter, outs []packet2.IPacket) error {
+	var size int
+	for _, out := range outs {
+		size += out.Len()
+	}
+	defer func() {
+		for _, out := range outs {
+			packet2.Return(out)
 		}
+		ts.buf.OnSent(size)
+	}()
+
+	ts.wmu.Lock()
+	defer ts.wmu.Unlock()
+	if ts.resume != nil {
+		for _, out := range outs {
+			ts.resume.window.push(out.Data())
+		}
+	}
+	n, err := vw.write(conn, outs, ts.writeTimeout)
+	if err != nil {
+		_ = conn.Close()
 		return err
 	}
+	ts.m.IncrementRealOutboundTraffic(uint64(n))
 	return nil
 }
 
-func (ts *TcpServerConn) sendData(data []byte) error {
-	if err := ts.conn.SetWriteDeadline(time.Now().Add(ts.writeTimeout)); err != nil {
+// sendFrame 绕过发送协程向当前连接写出一帧控制消息, 与发送协程互斥
+func (ts *TcpServerConn) sendFrame(body []byte, h int8) error {
+	ts.wmu.Lock()
+	defer ts.wmu.Unlock()
+	conn, codec := ts.link()
+	pack := codec.EncodeBody(body, h)
+	defer packet2.Return(pack)
+	return ts.write(conn, pack.Data())
+}
+
+func (ts *TcpServerConn) write(conn net.Conn, data []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(ts.writeTimeout)); err != nil {
 		return err
 	}
-	_, err := ts.conn.Write(data)
+	_, err := conn.Write(data)
 	return err
 }
 
+// HandleLoop 读取数据帧, 每一帧读入一个池化的 packet.Buffer, body 只在密钥交换阶段使用.
+// 开启会话恢复时连接异常断开后等待客户端恢复会话, 恢复后在新连接上继续读取
 func (ts *TcpServerConn) HandleLoop(header, body []byte) {
-	var (
-		head int8
-		err  error
-		data []byte
-	)
+	var err error
 
 	defer utils.RecoverPanic()
 	defer func() {
-		if err != nil {
-			if err == io.EOF || IsClosedConnError(err) {
-				ts.r.OnClose(ErrCanceled)
-			} else {
-				ts.r.OnClose(err)
+		ts.stopDrain()
+		close(ts.done)
+		reason := ErrCanceled
+		if closeErr := ts.closeErr.Load(); closeErr != nil {
+			reason = *closeErr
+		} else if err != nil {
+			var ne net.Error
+			switch {
+			case err == io.EOF || IsClosedConnError(err):
+			case errors.As(err, &ne) && ne.Timeout():
+				reason = ErrIdleTimeout
+			default:
+				reason = err
 			}
-		} else {
-			ts.r.OnClose(ErrCanceled)
 		}
+		ts.r.OnClose(reason)
+		ts.m.onClose(reason)
 
+		if ts.resume != nil {
+			resumeSessions.remove(ts)
+			ts.resume.release()
+		}
 		ts.buf.OnClose()
 		if ts.conn != nil {
 			_ = ts.conn.Close()
@@ -134,48 +399,236 @@ func (ts *TcpServerConn) HandleLoop(header, body []byte) {
 	}()
 
 	for {
-		data, head, err = ts.codec.BlockDecodeBody(ts.conn, header, body)
-		if err != nil {
+		err = ts.read(header)
+		if ts.resume == nil {
 			return
 		}
+		if err = ts.awaitResume(err); err != nil {
+			return
+		}
+	}
+}
+
+// read 读取当前连接上的数据帧, 直到出错
+func (ts *TcpServerConn) read(header []byte) error {
+	for {
+		data, buf, head, err := ts.codec.BlockDecodeBuffer(ts.conn, header)
+		if err != nil {
+			return err
+		}
+
+		ts.active()
+		if ts.in != nil && !ts.in.Allow(len(data)+HeadLen, ts.remoteAddr()) {
+			buf.Release()
+			ts.setCloseErr(ErrRateLimited)
+			return ErrRateLimited
+		}
 
 		switch head {
 		case mnetwork.TypeMessageHeartbeat:
-			ts.sendHeartbeatAck()
+			timestamp, echo := decodeHeartbeat(data)
+			ts.sendHeartbeatAck(timestamp)
+			ts.rtt.onEcho(echo, ts.m)
 			ts.heartbeat()
+		case mnetwork.TypeMessageSeqAck:
+			if ts.resume != nil {
+				ts.resume.onSeqAck(data)
+			}
+		case mnetwork.TypeMessageClose:
+			//开启会话恢复的客户端主动关闭, 不再等待恢复
+			buf.Release()
+			if ts.resume != nil {
+				ts.resume.close()
+			}
+			return io.EOF
 		default:
-			ts.m.IncrementRealInboundTraffic(uint64(len(data) + 4 + 2))
-			if err = ts.OnData(data); err != nil {
-				return
+			ts.m.IncrementRealInboundTraffic(uint64(HeadLen) + uint64(binary.BigEndian.Uint32(header)))
+			err = ts.OnData(data, buf)
+			if err == nil && ts.resume != nil && ts.resume.ackDue() {
+				ts.sendSeqAck()
 			}
 		}
+		buf.Release()
+		if err != nil {
+			return err
+		}
 	}
 }
 
-func (ts *TcpServerConn) OnData(data []byte) error {
-	if len(data) > 0 {
-		r := packet2.ReaderP(data)
-		for len(r.Remain()) > 0 {
-			if bytes, err := r.ReadBytes32(); err == nil {
-				ts.m.IncrementInboundTraffic(uint64(len(bytes)))
-				ts.r.Put(bytes, nil)
+// awaitResume 连接异常断开后暂停发送, 等待客户端在 GracePeriod 内携带会话令牌重连.
+// 在新连接上恢复会话后返回 nil, 否则返回会话关闭的原因; 服务端主动断开、调用 Close 或者服务端停止时不等待
+func (ts *TcpServerConn) awaitResume(cause error) error {
+	rs := ts.resume
+	if ts.closeErr.Load() != nil || rs.isClosed() || ts.ctx.Err() != nil {
+		return cause
+	}
+	ts.buf.Pause()
+	<-ts.sw.Done()
+	rs.detach()
+	ts.logger.Info("Session detached, wait for resume", zap.String("Addr", ts.remoteAddr()), zap.Error(cause))
+
+	timer := time.NewTimer(rs.grace)
+	defer timer.Stop()
+	for {
+		select {
+		case l := <-rs.attach:
+			err := ts.attach(l)
+			if err == nil {
+				return nil
 			}
+			close(l.done)
+			if errors.Is(err, mnetwork.ErrReplayWindowExceeded) {
+				return err
+			}
+		case <-timer.C:
+			return mnetwork.ErrSessionExpired
+		case <-rs.closed:
+			return cause
+		case <-ts.ctx.Done():
+			return cause
+		}
+	}
+}
+
+// attach 在新连接 l 上恢复会话: 回复会话恢复应答, 重放客户端未收到的消息后恢复发送
+func (ts *TcpServerConn) attach(l *resumeLink) error {
+	rs := ts.resume
+	rs.window.ack(l.recvSeq)
+	batches, reject := rs.window.since(l.recvSeq)
+	ack := mnetwork.EncodeResumeAck(&mnetwork.ResumeAck{Token: rs.token, RecvSeq: rs.recvSeq}, reject)
+	if err := writeFrame(l.conn, l.codec, ack, mnetwork.TypeMessageResumeAck); err != nil {
+		return err
+	}
+	if reject != nil {
+		return reject
+	}
+	if err := replay(l.conn, l.codec, batches); err != nil {
+		return err
+	}
+
+	addr := l.conn.RemoteAddr().String()
+	ts.mu.Lock()
+	ts.addr, ts.conn, ts.codec = addr, l.conn, l.codec
+	ts.sw = ts.newSender(l.conn, l.codec)
+	ts.mu.Unlock()
+	rs.attached(l)
+	ts.active()
+	ts.buf.Run(ts.sw)
+	ts.logger.Info("Session resumed", zap.String("Addr", addr))
+	return nil
+}
+
+// OnData 将 Raw 帧拆分为多条消息投递到接收队列, 消息直接引用 buf 的内存, 每条消息持有 buf 的一个引用
+func (ts *TcpServerConn) OnData(data []byte, buf *packet2.Buffer) error {
+	for len(data) > 0 {
+		item, remain, ok := nextItem(data)
+		if !ok {
+			return ErrMalformedFrame
+		}
+		data = remain
+		ts.m.IncrementInboundTraffic(uint64(len(item)))
+		if ts.resume != nil {
+			ts.resume.onRecv(len(item))
 		}
+		buf.Retain()
+		ts.r.PutMessage(mnetwork.NewMessage(item, buf))
 	}
 	return nil
 }
 
-func (ts *TcpServerConn) sendHeartbeatAck() {
-	ack := ts.codec.EncodeBody(nil, mnetwork.TypeMessageHeartbeat)
-	if err := ts.sendData(ack.Data()); err != nil {
+// RecvMessage 与 Recv 相同, 返回的消息引用池化的缓冲区, 使用完毕后需要调用 Message.Release
+func (ts *TcpServerConn) RecvMessage(ctx context.Context) (mnetwork.Message, error) {
+	return ts.r.RecvMessage(ctx)
+}
+
+// nextItem 从 Raw 帧中读取一条消息: size<int32> | data, 返回的 item 容量被截断, append 不会覆盖后续消息
+func nextItem(data []byte) (item, remain []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	size := binary.BigEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, nil, false
+	}
+	end := 4 + int(size)
+	return data[4:end:end], data[end:], true
+}
+
+// sendHeartbeatAck 回复心跳, 回显对端心跳帧的 timestamp 供对端计算 RTT
+func (ts *TcpServerConn) sendHeartbeatAck(timestamp int64) {
+	if err := ts.sendFrame(heartbeatBody(timestamp), mnetwork.TypeMessageHeartbeat); err != nil {
 		ts.logger.Error("Send heartbeat ack failed", zap.Error(err))
 	}
-	packet2.Return(ack)
+}
+
+// sendSeqAck 向对端确认已收到的消息序号
+func (ts *TcpServerConn) sendSeqAck() {
+	if err := ts.sendFrame(mnetwork.EncodeSeqAck(ts.resume.recvSeq), mnetwork.TypeMessageSeqAck); err != nil {
+		ts.logger.Error("Send seq ack failed", zap.Error(err))
+	}
+}
+
+// drain 将已缓存的数据发送完毕后向对端发送关闭通知, 连接由对端主动断开,
+// 此后 Send 返回 ErrDisconnected, Recv 仍然可以读取对端断开前发送的数据
+func (ts *TcpServerConn) drain() {
+	ts.buf.Drain()
+	ts.mu.Lock()
+	sw := ts.sw
+	ts.mu.Unlock()
+	<-sw.Done()
+
+	if err := ts.sendFrame(nil, mnetwork.TypeMessageClose); err != nil {
+		ts.logger.Error("Send close notice failed", zap.String("Addr", ts.remoteAddr()), zap.Error(err))
+		conn, _ := ts.link()
+		_ = conn.Close()
+	}
+}
+
+// keepalive 服务端心跳检测: 对端空闲超过 interval 时发送探测帧(TypeMessageProbe),
+// 超过 timeout 未收到任何数据帧时关闭连接, Recv 返回 ErrHeartbeatTimeout
+func (ts *TcpServerConn) keepalive(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			//等待恢复期间由 GracePeriod 控制会话的保留时长
+			if ts.resume != nil && ts.resume.detached.Load() {
+				continue
+			}
+			idle := time.Since(time.Unix(0, ts.lastActive.Load()))
+			if idle > timeout {
+				ts.logger.Error("Heartbeat timeout", zap.String("Addr", ts.remoteAddr()), zap.Duration("Idle", idle))
+				ts.closeWithErr(ErrHeartbeatTimeout)
+				return
+			}
+			if idle >= interval {
+				_ = ts.sendFrame(heartbeatBody(0), mnetwork.TypeMessageProbe)
+			}
+		case <-ts.done:
+			return
+		}
+	}
+}
+
+func (ts *TcpServerConn) closeWithErr(err error) {
+	ts.setCloseErr(err)
+	conn, _ := ts.link()
+	_ = conn.Close()
+}
+
+func (ts *TcpServerConn) setCloseErr(err error) {
+	ts.closeErr.CompareAndSwap(nil, &err)
+}
+
+func (ts *TcpServerConn) active() {
+	ts.lastActive.Store(time.Now().UnixNano())
 }
 
 func (ts *TcpServerConn) heartbeat() {
 	fields := []zap.Field{
-		zap.String("Addr", ts.addr),
+		zap.String("Addr", ts.remoteAddr()),
 		zap.Time("Time", time.Now()),
 	}
 	fields = append(fields, ts.m.Log()...)
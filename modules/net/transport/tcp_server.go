@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 
 	gnetwork "github.com/orbit-w/meteor/modules/net/network"
@@ -28,16 +29,14 @@ func (t *TcpServer) Serve(host string, _handle func(conn IConn), op *gnetwork.Ac
 	if err != nil {
 		return err
 	}
+	if op.TLSConfig != nil {
+		listener = tls.NewListener(listener, op.TLSConfig)
+	}
 
 	server := new(gnetwork.Server)
 	server.Serve(gnetwork.TCP, listener, func(ctx context.Context, generic net.Conn, head, body []byte,
 		options *gnetwork.AcceptorOptions) {
-		conn := NewTcpServerConn(ctx, generic, options.MaxIncomingPacket, head, body,
-			options.ReadTimeout, options.WriteTimeout, op.IsGzip, op.NeedToMonitor)
-		defer func() {
-			_ = conn.Close()
-		}()
-		_handle(conn)
+		serveConn(ctx, generic, head, body, options, _handle)
 	}, op)
 	t.server = server
 	return nil
@@ -50,6 +49,15 @@ func (t *TcpServer) Addr() string {
 	return ""
 }
 
+// GracefulStop 停止接收新连接, 通知所有存活连接在发送完已缓存的数据后关闭,
+// 阻塞直到所有 _handle 返回; ctx 结束时强制关闭剩余连接并返回 ctx.Err()
+func (t *TcpServer) GracefulStop(ctx context.Context) error {
+	if t.server != nil {
+		return t.server.GracefulStop(ctx)
+	}
+	return nil
+}
+
 // Stop stops the server
 // 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
 func (t *TcpServer) Stop() error {
@@ -0,0 +1,36 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+/*
+   @Author: orbit-w
+   @File: tls
+   @2026 10月 周日 15:10
+*/
+
+// clientHandshake 以客户端身份在 conn 上完成 TLS 握手, 握手失败时关闭 conn.
+// conf 未指定 ServerName 时使用拨号地址中的主机名
+func clientHandshake(conn net.Conn, remoteAddr string, conf *tls.Config) (net.Conn, error) {
+	if conf.ServerName == "" && !conf.InsecureSkipVerify {
+		conf = conf.Clone()
+		if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+			conf.ServerName = host
+		} else {
+			conf.ServerName = remoteAddr
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), HandshakeTimeout)
+	defer cancel()
+
+	tlsConn := tls.Client(conn, conf)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
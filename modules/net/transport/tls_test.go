@@ -0,0 +1,146 @@
+package transport
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: tls_test
+   @2026 10月 周日 15:32
+*/
+
+func Test_TLSEcho(t *testing.T) {
+	ca := newTestCA(t)
+	conf := DefaultServerConfig()
+	conf.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server", true)},
+	}
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithTLSConfig(&tls.Config{
+		RootCAs: ca.pool,
+	}))
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello, tls")
+}
+
+func Test_MutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	conf := DefaultServerConfig()
+	conf.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server", true)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.pool,
+	}
+	server := serveEcho(t, conf)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithTLSConfig(&tls.Config{
+		RootCAs:      ca.pool,
+		Certificates: []tls.Certificate{ca.issue(t, "client", false)},
+	}))
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello, mtls")
+
+	//未提供客户端证书, 服务端拒绝连接
+	rejected := DialWithOps(context.Background(), server.Addr(), WithTLSConfig(&tls.Config{
+		RootCAs: ca.pool,
+	}))
+	defer func() {
+		_ = rejected.Close()
+	}()
+	_ = rejected.Send([]byte("hello"))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err := rejected.Recv(ctx)
+	assert.Error(t, err)
+}
+
+func serveEcho(t *testing.T, conf *Config) IServer {
+	server, err := ServeByConfig("tcp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	return server
+}
+
+func assertEcho(t *testing.T, conn IConn, msg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte(msg)))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, string(in))
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "meteor test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+func (ca *testCA) issue(t *testing.T, name string, isServer bool) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if isServer {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"crypto/tls"
 	"time"
 
 	"github.com/orbit-w/meteor/modules/net/network"
@@ -21,13 +22,54 @@ type IConn interface {
 	Close() error
 }
 
+// IPrioritySender 支持发送优先级的连接实现的接口
+type IPrioritySender interface {
+	// SendWithPriority 与 Send 相同, data 写入 p 对应的发送通道: flush 时优先发送 PriorityUrgent 通道,
+	// PriorityBulk 通道有积压时每连续 UrgentBurst 个 urgent 批次后发送一个 bulk 批次. 不同通道之间不保证顺序
+	SendWithPriority(data []byte, p Priority) error
+}
+
+// IMessageReceiver 支持池化接收的连接实现的接口
+type IMessageReceiver interface {
+	// RecvMessage 与 Recv 相同, 返回的消息引用池化的缓冲区(同一帧内的消息共享一个缓冲区),
+	// 使用完毕后调用 Message.Release 归还, 之后不能再访问 Message.Data. Recv 返回的数据则由调用方长期持有
+	RecvMessage(ctx context.Context) (network.Message, error)
+}
+
+// RecvMessage 接收 conn 的一条消息, conn 实现 IMessageReceiver 时使用池化的缓冲区,
+// 否则退化为 Recv, 此时 Message.Release 什么也不做. 使用完毕后都需要调用 Message.Release
+func RecvMessage(ctx context.Context, conn IConn) (network.Message, error) {
+	if r, ok := conn.(IMessageReceiver); ok {
+		return r.RecvMessage(ctx)
+	}
+	in, err := conn.Recv(ctx)
+	return network.NewMessage(in, nil), err
+}
+
+// IHandshake 开启握手的服务端连接实现的接口
+type IHandshake interface {
+	// Handshake 返回客户端的握手信息, 未开启握手时返回 nil
+	Handshake() *network.HandshakeRequest
+}
+
+// IBackpressure 带发送队列的连接(TCP, KCP, WebSocket)实现的背压接口
+type IBackpressure interface {
+	// SendContext 与 Send 相同, 发送队列策略为 QueuePolicyBlock 时阻塞等待可以通过 ctx 取消
+	SendContext(ctx context.Context, data []byte) error
+	// QueuedBytes 当前积压的发送字节数
+	QueuedBytes() int
+}
+
 type ITransportServer interface {
 	Serve(host string, _handle func(conn IConn), op *network.AcceptorOptions) error
 	Addr() string
 	Stop() error
+	// GracefulStop 停止接收新连接并通知所有存活连接关闭, 阻塞直到所有 _handle 返回或者 ctx 结束
+	GracefulStop(ctx context.Context) error
 }
 
 type DialOption struct {
+	Protocol          network.Protocol //传输协议, 默认 TCP
 	MaxIncomingPacket uint32
 	IsBlock           bool
 	IsGzip            bool
@@ -35,6 +77,46 @@ type DialOption struct {
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
 	DisconnectHandler func()
+
+	Compressor        network.CompressorID //压缩算法, 为 CompressNone 且 IsGzip 为 true 时使用 gzip
+	CompressThreshold int                  //压缩阈值, 0 时取 ZMinLen, 小于 0 时压缩所有消息
+
+	TLSConfig       *tls.Config           //不为空时使用 TLS 建立连接
+	Reconnect       bool                  //断线后自动重连
+	MaxPendingBytes int                   //断线期间允许缓存的最大发送字节数, 0 表示不限制
+	StateHandler    func(state ConnState) //连接状态变化回调, 在连接协程中同步调用, 不能阻塞
+
+	Encryption *network.EncryptionOptions //不为空时连接建立后先完成密钥交换, 之后的数据帧加密传输
+	SendQueue  network.SendQueueOptions   //连接建立后发送队列的高低水位与背压策略, 不支持 UDP
+	MemLink    *MemLinkOptions            //network.MEM 连接的链路模拟参数
+	Chaos      *network.ChaosOptions      //不为空时为每条连接(包括重连)注入故障, 不支持 UDP
+	Handshake  *network.HandshakeRequest  //不为空时连接建立后(开启加密时在密钥交换之后)先完成握手, 不支持 UDP
+	Resume     bool                       //开启会话恢复, 断线重连后在新连接上继续原来的会话, 不支持 UDP
+}
+
+// ConnState 客户端连接状态
+type ConnState int8
+
+const (
+	ConnStateConnecting   ConnState = iota //正在建立连接(包括断线重连)
+	ConnStateConnected                     //首次连接成功
+	ConnStateDisconnected                  //连接断开
+	ConnStateReconnected                   //断线重连成功
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnStateConnecting:
+		return "connecting"
+	case ConnStateConnected:
+		return "connected"
+	case ConnStateDisconnected:
+		return "disconnected"
+	case ConnStateReconnected:
+		return "reconnected"
+	default:
+		return "unknown"
+	}
 }
 
 func DefaultDialOption() *DialOption {
@@ -62,6 +144,11 @@ func DefaultDevelopDialOption(isGzip bool) *DialOption {
 	}
 }
 
+func (dp *DialOption) newCodec() *network.Codec {
+	return network.NewCodecWithCompressor(dp.MaxIncomingPacket, network.ResolveCompressor(dp.Compressor, dp.IsGzip),
+		network.ResolveCompressThreshold(dp.CompressThreshold), dp.ReadTimeout)
+}
+
 type ConnOption struct {
 	MaxIncomingPacket uint32
 }
@@ -84,6 +171,60 @@ func WithGzip(gzip bool) Opt {
 	}
 }
 
+// WithCompressor 指定发送消息使用的压缩算法以及压缩阈值, threshold 为 0 时取 ZMinLen.
+// 解压根据消息头中的压缩算法 ID 进行, 因此两端可以使用不同的压缩算法
+func WithCompressor(id network.CompressorID, threshold int) Opt {
+	return func(dp *DialOption) {
+		dp.Compressor = id
+		dp.CompressThreshold = threshold
+	}
+}
+
+// WithEncryption 开启应用层加密, 需要服务端同样配置 Config.Encryption.
+// op.PeerPublicKey 不为空时校验服务端公钥, 防止中间人攻击
+func WithEncryption(op *network.EncryptionOptions) Opt {
+	return func(dp *DialOption) {
+		dp.Encryption = op
+	}
+}
+
+// WithProtocol 指定客户端使用的传输协议, 支持 network.TCP, network.KCP, network.UDP, network.WS 与 network.MEM
+func WithProtocol(p network.Protocol) Opt {
+	return func(dp *DialOption) {
+		dp.Protocol = p
+	}
+}
+
+// WithMemLink 设置 network.MEM 连接的链路模拟参数: 时延、丢包、乱序以及使用的时钟
+func WithMemLink(op *MemLinkOptions) Opt {
+	return func(dp *DialOption) {
+		dp.MemLink = op
+	}
+}
+
+// WithChaos 为客户端的每条连接注入故障, 阶段从连接建立时开始计时
+func WithChaos(op *network.ChaosOptions) Opt {
+	return func(dp *DialOption) {
+		dp.Chaos = op
+	}
+}
+
+// WithHandshake 连接建立后向服务端发送 req 完成握手, 服务端拒绝时不再重试, Recv 返回 network.ErrHandshakeRejected
+func WithHandshake(req *network.HandshakeRequest) Opt {
+	return func(dp *DialOption) {
+		dp.Handshake = req
+	}
+}
+
+// WithResume 开启会话恢复(同时开启断线重连): 服务端需要配置 Config.Resume, 断线重连后双方重放对端未收到的消息;
+// 会话已过期或者缺失的消息已移出重放窗口时不再重连, Recv 返回 network.ErrSessionExpired 或者 network.ErrReplayWindowExceeded
+func WithResume() Opt {
+	return func(dp *DialOption) {
+		dp.Resume = true
+		dp.Reconnect = true
+	}
+}
+
 func WithMaxIncomingPacket(maxIncomingPacket uint32) Opt {
 	return func(dp *DialOption) {
 		dp.MaxIncomingPacket = maxIncomingPacket
@@ -96,6 +237,36 @@ func WithNeedToMonitor(needToMonitor bool) Opt {
 	}
 }
 
+// WithReconnect 开启断线自动重连, 断线期间 Send 的数据缓存在 ControlBuffer 中,
+// 缓存超过 maxPendingBytes 时 Send 返回 ErrSendQueueFull
+func WithReconnect(maxPendingBytes int) Opt {
+	return func(dp *DialOption) {
+		dp.Reconnect = true
+		dp.MaxPendingBytes = maxPendingBytes
+	}
+}
+
+// WithSendQueue 设置发送队列的高低水位与背压策略, 积压字节数超过 HighWatermark 时按 Policy 处理;
+// 断线重连期间的缓存仍由 WithReconnect 的 maxPendingBytes 限制
+func WithSendQueue(op network.SendQueueOptions) Opt {
+	return func(dp *DialOption) {
+		dp.SendQueue = op
+	}
+}
+
+// WithTLSConfig 使用 TLS 建立连接, 服务端要求客户端证书时需要在 conf.Certificates 中提供
+func WithTLSConfig(conf *tls.Config) Opt {
+	return func(dp *DialOption) {
+		dp.TLSConfig = conf
+	}
+}
+
+func WithStateHandler(handler func(state ConnState)) Opt {
+	return func(dp *DialOption) {
+		dp.StateHandler = handler
+	}
+}
+
 func WithTimeout(readTimeout, writeTimeout time.Duration) Opt {
 	return func(dp *DialOption) {
 		dp.ReadTimeout = readTimeout
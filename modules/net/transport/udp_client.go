@@ -0,0 +1,209 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/orbit-w/meteor/bases/misc/utils"
+	"github.com/orbit-w/meteor/modules/mlog"
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"go.uber.org/zap"
+)
+
+/*
+   @Author: orbit-w
+   @File: udp_client
+   @2026 10月 周日 11:30
+*/
+
+// UdpClient implements the IConn interface with UDP datagrams.
+// 每次 Send 对应一个数据报, 不保证可靠与有序; 客户端周期性发送心跳以维持服务端的虚拟连接,
+// 超过 PingTimeOut 未收到任何数据报时连接关闭, Recv 返回 ErrIdleTimeout
+type UdpClient struct {
+	state      atomic.Uint32
+	lastAck    atomic.Int64
+	remoteAddr string
+	conn       net.Conn
+	ctx        context.Context
+	cancel     context.CancelFunc
+	codec      *mnetwork.Codec
+	r          *mnetwork.BlockReceiver
+	m          *Monitor
+	logger     *mlog.Logger
+	rtt        rttStats
+}
+
+func dialUdpContext(ctx context.Context, remoteAddr string, dp *DialOption) IConn {
+	_ctx, cancel := context.WithCancel(ctx)
+	uc := &UdpClient{
+		remoteAddr: remoteAddr,
+		ctx:        _ctx,
+		cancel:     cancel,
+		codec:      dp.newCodec(),
+		r:          mnetwork.NewBlockReceiver(),
+		logger:     newUdpClientPrefixLogger(),
+	}
+	uc.m = newMonitor(dp.NeedToMonitor, clientMetrics)
+
+	conn, err := net.Dial("udp", remoteAddr)
+	if err != nil {
+		uc.state.Store(cliStateStopped)
+		uc.r.OnClose(err)
+		cancel()
+		return uc
+	}
+
+	uc.conn = conn
+	uc.m.onOpen()
+	uc.ack()
+	go uc.reader()
+	go uc.keepalive()
+	return uc
+}
+
+// Send 将 out 编码为单个数据报立即发送, 编码后超过 MaxDatagramSize 时返回 ErrDatagramTooLarge
+func (uc *UdpClient) Send(out []byte) error {
+	if len(out) == 0 {
+		return nil
+	}
+	if uc.state.Load() != cliStateNormal {
+		return ErrDisconnected
+	}
+
+	pack, err := uc.codec.Encode(out, mnetwork.TypeMessageRaw)
+	if err != nil {
+		return err
+	}
+	defer packet2.Return(pack)
+
+	if err = uc.write(pack.Data()); err != nil {
+		return err
+	}
+	uc.m.IncrementOutboundTraffic(uint64(len(out)))
+	uc.m.IncrementRealOutboundTraffic(uint64(pack.Len()))
+	return nil
+}
+
+// SendWithPriority UDP 没有发送队列, 等同于 Send
+func (uc *UdpClient) SendWithPriority(out []byte, _ Priority) error {
+	return uc.Send(out)
+}
+
+func (uc *UdpClient) Recv(ctx context.Context) ([]byte, error) {
+	return uc.r.Recv(ctx)
+}
+
+// RecvMessage 与 Recv 相同, 返回的消息使用完毕后需要调用 Message.Release
+func (uc *UdpClient) RecvMessage(ctx context.Context) (mnetwork.Message, error) {
+	return uc.r.RecvMessage(ctx)
+}
+
+// Stats 连接质量统计
+func (uc *UdpClient) Stats() ConnStats {
+	return uc.rtt.Stats()
+}
+
+func (uc *UdpClient) Close() error {
+	uc.closeWithErr(ErrCanceled)
+	return nil
+}
+
+func (uc *UdpClient) closeWithErr(err error) {
+	if uc.state.CompareAndSwap(cliStateNormal, cliStateStopped) {
+		uc.r.OnClose(err)
+		uc.cancel()
+		if uc.conn != nil {
+			uc.m.onClose(err)
+			_ = uc.conn.Close()
+		}
+	}
+}
+
+func (uc *UdpClient) write(datagram []byte) error {
+	if len(datagram) > MaxDatagramSize {
+		return ErrDatagramTooLarge
+	}
+	_, err := uc.conn.Write(datagram)
+	return err
+}
+
+func (uc *UdpClient) reader() {
+	defer utils.RecoverPanic()
+	buf := make([]byte, MaxDatagramSize)
+	for {
+		n, err := uc.conn.Read(buf)
+		if err != nil {
+			//服务端未启动或重启期间会收到 ICMP 端口不可达, 交由心跳超时处理
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				continue
+			}
+			if IsClosedConnError(err) {
+				uc.closeWithErr(ErrCanceled)
+			} else {
+				uc.closeWithErr(err)
+			}
+			return
+		}
+
+		data, head, err := uc.codec.DecodeDatagram(buf[:n])
+		if err != nil {
+			uc.logger.Error("Decode datagram failed", zap.String("RemoteAddr", uc.remoteAddr), zap.Error(err))
+			continue
+		}
+
+		uc.ack()
+		if head == mnetwork.TypeMessageClose {
+			uc.closeWithErr(ErrServerShutdown)
+			return
+		}
+		if head == mnetwork.TypeMessageHeartbeat {
+			_, echo := decodeHeartbeat(data)
+			uc.rtt.onEcho(echo, uc.m)
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		uc.m.IncrementRealInboundTraffic(uint64(n))
+		uc.m.IncrementInboundTraffic(uint64(len(data)))
+		//读缓冲区会被复用, 拷贝到池化的缓冲区后投递
+		in := packet2.GetBuffer(len(data))
+		copy(in.Bytes(), data)
+		uc.r.PutMessage(mnetwork.NewMessage(in.Bytes(), in))
+	}
+}
+
+func (uc *UdpClient) keepalive() {
+	ticker := time.NewTicker(AckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(time.Unix(0, uc.lastAck.Load())) > PingTimeOut {
+				uc.logger.Error("No heartbeat", zap.String("RemoteAddr", uc.remoteAddr))
+				uc.closeWithErr(ErrIdleTimeout)
+				return
+			}
+			ping := encodeHeartbeat(uc.codec, mnetwork.TypeMessageHeartbeat, 0)
+			_ = uc.write(ping.Data())
+			packet2.Return(ping)
+		case <-uc.ctx.Done():
+			return
+		}
+	}
+}
+
+func (uc *UdpClient) ack() {
+	uc.lastAck.Store(time.Now().UnixNano())
+}
+
+func newUdpClientPrefixLogger() *mlog.Logger {
+	return mlog.With(zap.String("TransportModel", "UdpClient"))
+}
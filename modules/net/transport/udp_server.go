@@ -0,0 +1,393 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/orbit-w/meteor/bases/misc/utils"
+	"github.com/orbit-w/meteor/modules/mlog"
+	gnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"go.uber.org/zap"
+)
+
+/*
+   @Author: orbit-w
+   @File: udp_server
+   @2026 10月 周日 11:02
+*/
+
+func init() {
+	RegisterFactory(gnetwork.UDP, func() ITransportServer {
+		return &UdpServer{}
+	})
+}
+
+// UdpServer 基于 UDP 数据报的传输层服务端
+// 以对端地址为 key 为每个对端建立一个虚拟 IConn, 每个数据报承载一帧 network.Codec 消息,
+// 不保证可靠与有序, 适用于状态同步等允许丢包的场景.
+// 虚拟连接超过 ReadTimeout 未收到任何数据报时会被驱逐, Recv 返回 ErrIdleTimeout.
+// 新对端在建立虚拟连接前经过 AcceptorOptions.Limit 的接入限流, 虚拟连接总数不超过 MaxUdpSessions
+type UdpServer struct {
+	state    atomic.Uint32
+	conn     net.PacketConn
+	ctx      context.Context
+	cancel   context.CancelFunc
+	handle   func(conn IConn)
+	op       *gnetwork.AcceptorOptions
+	codec    *gnetwork.Codec
+	limiter  *gnetwork.ConnLimiter
+	mu       sync.RWMutex
+	sessions map[string]*UdpServerConn
+	wg       sync.WaitGroup //虚拟连接的处理协程
+	logger   *mlog.Logger
+}
+
+func (u *UdpServer) Serve(host string, _handle func(conn IConn), op *gnetwork.AcceptorOptions) error {
+	conn, err := net.ListenPacket("udp", host)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u.conn = conn
+	u.ctx = ctx
+	u.cancel = cancel
+	u.handle = _handle
+	u.op = op
+	u.codec = op.NewCodec()
+	u.limiter = gnetwork.NewConnLimiter(&op.Limit)
+	u.sessions = make(map[string]*UdpServerConn)
+	u.logger = newUdpServerPrefixLogger()
+	u.state.Store(TypeWorking)
+
+	go u.readLoop()
+	go u.evictLoop()
+	return nil
+}
+
+func (u *UdpServer) Addr() string {
+	if u.conn != nil {
+		return u.conn.LocalAddr().String()
+	}
+	return ""
+}
+
+// Stop stops the server
+// 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
+func (u *UdpServer) Stop() error {
+	u.stop(false)
+	return nil
+}
+
+// stop 关闭所有虚拟连接, notify 为 true 时先向对端发送关闭通知
+func (u *UdpServer) stop(notify bool) {
+	if u.state.CompareAndSwap(TypeWorking, TypeStopped) {
+		if u.cancel != nil {
+			u.cancel()
+		}
+
+		u.mu.Lock()
+		sessions := u.sessions
+		u.sessions = make(map[string]*UdpServerConn)
+		u.mu.Unlock()
+		for _, sess := range sessions {
+			if notify {
+				sess.sendCloseNotice()
+			}
+			sess.onClose(ErrCanceled)
+		}
+
+		if u.conn != nil {
+			_ = u.conn.Close()
+		}
+	}
+}
+
+// GracefulStop 通知所有对端关闭并关闭虚拟连接, 阻塞直到所有 _handle 返回或者 ctx 结束
+func (u *UdpServer) GracefulStop(ctx context.Context) error {
+	u.stop(true)
+
+	done := make(chan struct{})
+	go func() {
+		u.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (u *UdpServer) readLoop() {
+	defer utils.RecoverPanic()
+	buf := make([]byte, MaxDatagramSize)
+	for {
+		n, addr, err := u.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-u.ctx.Done():
+				return
+			default:
+				if IsClosedConnError(err) {
+					return
+				}
+				continue
+			}
+		}
+
+		u.onDatagram(buf[:n], addr)
+	}
+}
+
+func (u *UdpServer) onDatagram(datagram []byte, addr net.Addr) {
+	data, head, err := u.codec.DecodeDatagram(datagram)
+	if err != nil {
+		//任何人都可以向端口发送数据报, 以 Debug 级别记录以免日志被刷屏
+		u.logger.Debug("Decode datagram failed", zap.String("Addr", addr.String()), zap.Error(err))
+		return
+	}
+
+	sess := u.session(addr)
+	if sess == nil {
+		return
+	}
+
+	sess.active()
+	if !sess.in.Allow(len(datagram), sess.addr) {
+		u.remove(sess)
+		sess.onClose(ErrRateLimited)
+		return
+	}
+	switch head {
+	case gnetwork.TypeMessageHeartbeat:
+		timestamp, _ := decodeHeartbeat(data)
+		sess.sendHeartbeatAck(timestamp)
+	default:
+		sess.onData(data, len(datagram))
+	}
+}
+
+// session 获取对端地址对应的虚拟连接, 不存在时新建并异步调用 handle;
+// 虚拟连接数达到 MaxUdpSessions 或者被接入限流拒绝时返回 nil, 数据报被丢弃
+func (u *UdpServer) session(addr net.Addr) *UdpServerConn {
+	key := addr.String()
+	u.mu.RLock()
+	sess := u.sessions[key]
+	u.mu.RUnlock()
+	if sess != nil {
+		return sess
+	}
+
+	u.mu.Lock()
+	if u.state.Load() != TypeWorking {
+		u.mu.Unlock()
+		return nil
+	}
+	if sess = u.sessions[key]; sess == nil {
+		if len(u.sessions) >= MaxUdpSessions || !u.limiter.AcquireAddr(addr) {
+			u.mu.Unlock()
+			return nil
+		}
+		sess = newUdpServerConn(u, addr)
+		u.sessions[key] = sess
+		u.wg.Add(1)
+		utils.GoRecoverPanic(func() {
+			defer func() {
+				_ = sess.Close()
+				u.wg.Done()
+			}()
+			u.handle(sess)
+		})
+	}
+	u.mu.Unlock()
+	return sess
+}
+
+func (u *UdpServer) remove(sess *UdpServerConn) {
+	u.mu.Lock()
+	if u.sessions[sess.addr] == sess {
+		delete(u.sessions, sess.addr)
+	}
+	u.mu.Unlock()
+}
+
+// evictLoop 周期性地驱逐超过 ReadTimeout 未活跃的虚拟连接
+func (u *UdpServer) evictLoop() {
+	timeout := u.op.ReadTimeout
+	if timeout <= 0 {
+		timeout = ReadTimeout
+	}
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deadline := time.Now().Add(-timeout).UnixNano()
+			var expired []*UdpServerConn
+			u.mu.RLock()
+			for _, sess := range u.sessions {
+				if sess.lastActive.Load() < deadline {
+					expired = append(expired, sess)
+				}
+			}
+			u.mu.RUnlock()
+
+			for _, sess := range expired {
+				u.remove(sess)
+				sess.onClose(ErrIdleTimeout)
+			}
+		case <-u.ctx.Done():
+			return
+		}
+	}
+}
+
+// UdpServerConn 服务端为每个 UDP 对端维护的虚拟连接
+type UdpServerConn struct {
+	state      atomic.Uint32
+	lastActive atomic.Int64
+	id         uint64
+	addr       string
+	remote     net.Addr
+	server     *UdpServer
+	in         *gnetwork.InboundLimiter
+	r          *gnetwork.BlockReceiver
+	m          *Monitor
+}
+
+func newUdpServerConn(server *UdpServer, remote net.Addr) *UdpServerConn {
+	op := server.op
+	uc := &UdpServerConn{
+		addr:   remote.String(),
+		remote: remote,
+		server: server,
+		in:     gnetwork.NewInboundLimiter(&op.Limit),
+		r:      gnetwork.NewBlockReceiver(),
+	}
+	uc.state.Store(TypeWorking)
+	uc.active()
+	uc.m = newMonitor(op.NeedToMonitor, op.Metrics)
+	uc.m.onOpen()
+	return uc
+}
+
+// Send 将 data 编码为单个数据报立即发送, 编码后超过 MaxDatagramSize 时返回 ErrDatagramTooLarge
+func (uc *UdpServerConn) Send(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if uc.state.Load() != TypeWorking {
+		return ErrDisconnected
+	}
+
+	pack, err := uc.server.codec.Encode(data, gnetwork.TypeMessageRaw)
+	if err != nil {
+		return err
+	}
+	defer packet2.Return(pack)
+
+	if err = uc.write(pack.Data()); err != nil {
+		return err
+	}
+	uc.m.IncrementOutboundTraffic(uint64(len(data)))
+	uc.m.IncrementRealOutboundTraffic(uint64(pack.Len()))
+	return nil
+}
+
+// SendWithPriority UDP 没有发送队列, 等同于 Send
+func (uc *UdpServerConn) SendWithPriority(data []byte, _ Priority) error {
+	return uc.Send(data)
+}
+
+func (uc *UdpServerConn) Recv(ctx context.Context) ([]byte, error) {
+	return uc.r.Recv(ctx)
+}
+
+// RecvMessage 与 Recv 相同, 返回的消息使用完毕后需要调用 Message.Release
+func (uc *UdpServerConn) RecvMessage(ctx context.Context) (gnetwork.Message, error) {
+	return uc.r.RecvMessage(ctx)
+}
+
+// ID 由 SessionManager 分配的连接 ID
+func (uc *UdpServerConn) ID() uint64 {
+	return uc.id
+}
+
+func (uc *UdpServerConn) setID(id uint64) {
+	uc.id = id
+}
+
+// sendEncoded UDP 每个数据报单独编码, 去掉 item 的长度前缀后直接发送
+func (uc *UdpServerConn) sendEncoded(item []byte) error {
+	return uc.Send(item[4:])
+}
+
+// pending UDP 立即发送, 没有发送队列
+func (uc *UdpServerConn) pending() int {
+	return 0
+}
+
+func (uc *UdpServerConn) Close() error {
+	uc.server.remove(uc)
+	uc.onClose(ErrCanceled)
+	return nil
+}
+
+func (uc *UdpServerConn) write(datagram []byte) error {
+	if len(datagram) > MaxDatagramSize {
+		return ErrDatagramTooLarge
+	}
+	_, err := uc.server.conn.WriteTo(datagram, uc.remote)
+	return err
+}
+
+func (uc *UdpServerConn) onData(data []byte, size int) {
+	if len(data) == 0 {
+		return
+	}
+	uc.m.IncrementRealInboundTraffic(uint64(size))
+	uc.m.IncrementInboundTraffic(uint64(len(data)))
+	//读缓冲区会被复用, 拷贝到池化的缓冲区后投递
+	in := packet2.GetBuffer(len(data))
+	copy(in.Bytes(), data)
+	uc.r.PutMessage(gnetwork.NewMessage(in.Bytes(), in))
+}
+
+func (uc *UdpServerConn) onClose(err error) {
+	if uc.state.CompareAndSwap(TypeWorking, TypeStopped) {
+		uc.server.limiter.ReleaseAddr(uc.remote)
+		uc.r.OnClose(err)
+		uc.m.onClose(err)
+	}
+}
+
+func (uc *UdpServerConn) active() {
+	uc.lastActive.Store(time.Now().UnixNano())
+}
+
+// sendHeartbeatAck 回复心跳, 回显对端心跳帧的 timestamp 供对端计算 RTT
+func (uc *UdpServerConn) sendHeartbeatAck(timestamp int64) {
+	ack := encodeHeartbeat(uc.server.codec, gnetwork.TypeMessageHeartbeat, timestamp)
+	if err := uc.write(ack.Data()); err != nil {
+		uc.server.logger.Error("Send heartbeat ack failed", zap.String("Addr", uc.addr), zap.Error(err))
+	}
+	packet2.Return(ack)
+}
+
+func (uc *UdpServerConn) sendCloseNotice() {
+	notice := uc.server.codec.EncodeBody(nil, gnetwork.TypeMessageClose)
+	_ = uc.write(notice.Data())
+	packet2.Return(notice)
+}
+
+func newUdpServerPrefixLogger() *mlog.Logger {
+	return mlog.With(zap.String("TransportModel", "UdpServer"))
+}
@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: udp_test
+   @2026 10月 周日 11:48
+*/
+
+func Test_UdpEcho(t *testing.T) {
+	server, err := Serve("udp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				break
+			}
+			_ = conn.Send(in)
+		}
+	})
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.UDP))
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for _, msg := range []string{"hello", "udp", "world"} {
+		assert.NoError(t, conn.Send([]byte(msg)))
+		in, err := conn.Recv(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, string(in))
+	}
+
+	assert.ErrorIs(t, conn.Send(make([]byte, MaxDatagramSize)), ErrDatagramTooLarge)
+}
+
+func Test_UdpIdleEviction(t *testing.T) {
+	conf := DefaultServerConfig()
+	conf.ReadTimeout = time.Millisecond * 200
+	closed := make(chan error, 1)
+	server, err := ServeByConfig("udp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			if _, err := conn.Recv(context.Background()); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.UDP))
+	defer func() {
+		_ = conn.Close()
+	}()
+	assert.NoError(t, conn.Send([]byte("hello")))
+
+	select {
+	case err = <-closed:
+		assert.ErrorIs(t, err, ErrIdleTimeout)
+	case <-time.After(time.Second * 3):
+		t.Fatal("virtual conn was not evicted")
+	}
+}
+
+func Test_UdpLimitMaxConns(t *testing.T) {
+	limited := make(chan network.LimitReason, 8)
+	conf := DefaultServerConfig()
+	conf.Limit = network.LimitOptions{
+		MaxConns: 1,
+		OnLimit: func(reason network.LimitReason, _ string) {
+			limited <- reason
+		},
+	}
+	server, err := ServeByConfig("udp", "127.0.0.1:0", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.UDP))
+	defer func() {
+		_ = conn.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	assert.NoError(t, conn.Send([]byte("hello")))
+	in, err := conn.Recv(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(in))
+
+	//超过最大虚拟连接数的对端不会建立会话, 数据报被丢弃
+	other := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.UDP))
+	defer func() {
+		_ = other.Close()
+	}()
+	assert.NoError(t, other.Send([]byte("hello")))
+	select {
+	case reason := <-limited:
+		assert.Equal(t, network.LimitMaxConns, reason)
+	case <-time.After(time.Second * 3):
+		t.Fatal("second peer was not limited")
+	}
+	assert.Equal(t, 1, server.Sessions().CCU())
+}
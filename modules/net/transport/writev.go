@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"net"
+	"time"
+
+	mnetwork "github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"golang.org/x/net/websocket"
+)
+
+/*
+   @Author: orbit-w
+   @File: writev
+   @2026 10月 周日 16:10
+*/
+
+// MaxCoalesce 发送协程一次 writev 最多合并的批次数
+const MaxCoalesce = 16
+
+// vectorWriter 发送协程私有的分段写状态: 将 ControlBuffer 输出的多个批次编码为帧,
+// 帧头与消息体作为独立的分段通过 net.Buffers 一次写出, 消息体不再拷贝. 不是线程安全的.
+// 只有流式连接会合并多帧, WebSocket 连接每帧对应一个消息
+type vectorWriter struct {
+	codec   *mnetwork.Codec
+	headers []byte
+	vec     net.Buffers
+}
+
+func newVectorWriter(codec *mnetwork.Codec) *vectorWriter {
+	return &vectorWriter{
+		codec:   codec,
+		headers: make([]byte, mnetwork.FrameHeadLen*MaxCoalesce),
+		vec:     make(net.Buffers, 0, 2*MaxCoalesce),
+	}
+}
+
+// write 将 packs 编码为 TypeMessageRaw 帧写出, 流式连接在一次写操作中写出, 返回写出的字节数. 调用方负责归还 packs
+func (vw *vectorWriter) write(conn net.Conn, packs []packet2.IPacket, timeout time.Duration) (int, error) {
+	if need := mnetwork.FrameHeadLen * len(packs); len(vw.headers) < need {
+		vw.headers = make([]byte, need)
+	}
+
+	vec := vw.vec[:0]
+	var size int
+	for i, pack := range packs {
+		header := vw.headers[i*mnetwork.FrameHeadLen : (i+1)*mnetwork.FrameHeadLen]
+		body, err := vw.codec.EncodeVector(header, pack.Data(), mnetwork.TypeMessageRaw)
+		if err != nil {
+			return 0, err
+		}
+		vec = append(vec, header, body)
+		size += len(header) + len(body)
+	}
+	vw.vec = vec
+
+	if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+
+	defer clear(vw.vec[:cap(vw.vec)])
+	switch conn.(type) {
+	case *net.TCPConn:
+		n, err := vec.WriteTo(conn)
+		return int(n), err
+	case *wsConn, *websocket.Conn:
+		//每个 WebSocket 消息只能承载一帧, 逐帧写出
+		var written int
+		for i := 0; i < len(vec); i += 2 {
+			n, err := writeMerged(conn, vec[i:i+2], len(vec[i])+len(vec[i+1]))
+			written += n
+			if err != nil {
+				return written, err
+			}
+		}
+		return written, nil
+	default:
+		//其他流式连接(TLS, KCP)逐段写出会与心跳等其他协程的写入交错, 先合并为一段
+		return writeMerged(conn, vec, size)
+	}
+}
+
+// writeMerged 将 segs 合并后以一次 Write 写出
+func writeMerged(conn net.Conn, segs net.Buffers, size int) (int, error) {
+	merged := packet2.WriterP(size)
+	if merged == nil {
+		merged = packet2.Writer(size)
+	}
+	defer packet2.Return(merged)
+	for _, seg := range segs {
+		merged.Write(seg)
+	}
+	return conn.Write(merged.Data())
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+/*
+   @Author: orbit-w
+   @File: ws_conn
+   @2026 10月 周日 16:20
+*/
+
+// wsConn 修正 websocket.Conn 的地址语义:
+// 服务端的 websocket.Conn.RemoteAddr 返回的是 Origin 而非对端地址
+type wsConn struct {
+	*websocket.Conn
+	remote net.Addr
+}
+
+func (c *wsConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+type wsAddr string
+
+func (a wsAddr) Network() string {
+	return "websocket"
+}
+
+func (a wsAddr) String() string {
+	return string(a)
+}
+
+// dialWebSocket 建立 WebSocket 连接, remoteAddr 可以是 host:port 或者完整的 ws://, wss:// 地址,
+// 使用 host:port 时根据是否配置 TLS 选择 ws 或 wss
+func dialWebSocket(remoteAddr string, tlsConf *tls.Config) (net.Conn, error) {
+	location := remoteAddr
+	if !strings.HasPrefix(location, "ws://") && !strings.HasPrefix(location, "wss://") {
+		if tlsConf != nil {
+			location = "wss://" + remoteAddr + "/"
+		} else {
+			location = "ws://" + remoteAddr + "/"
+		}
+	}
+
+	config, err := websocket.NewConfig(location, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	config.TlsConfig = tlsConf
+
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	conn.PayloadType = websocket.BinaryFrame
+	return conn, nil
+}
@@ -0,0 +1,163 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	gnetwork "github.com/orbit-w/meteor/modules/net/network"
+	"golang.org/x/net/websocket"
+)
+
+/*
+   @Author: orbit-w
+   @File: ws_server
+   @2026 10月 周日 16:05
+*/
+
+func init() {
+	RegisterFactory(gnetwork.WS, func() ITransportServer {
+		return &WsServer{}
+	})
+}
+
+// WsServer 基于 WebSocket 的传输层服务端, 供无法使用原生 TCP 的 H5 客户端接入
+// 每个二进制 WebSocket 消息承载一帧 network.Codec 数据, 客户端需要定期发送 TypeMessageHeartbeat 帧保活:
+// websocket 库在 Read 内部消化 ping/pong 控制帧, 不会刷新按帧设置的读超时
+type WsServer struct {
+	state    atomic.Uint32
+	listener net.Listener
+	server   *http.Server
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	headPool *sync.Pool
+	bodyPool *sync.Pool
+	limiter  *gnetwork.ConnLimiter
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]struct{} //存活连接, GracefulStop 超时时强制关闭
+	wg       sync.WaitGroup               //存活连接的处理协程
+}
+
+func (ws *WsServer) Serve(host string, _handle func(conn IConn), op *gnetwork.AcceptorOptions) error {
+	listener, err := net.Listen("tcp", host)
+	if err != nil {
+		return err
+	}
+	if op.TLSConfig != nil {
+		listener = tls.NewListener(listener, op.TLSConfig)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ws.listener = listener
+	ws.ctx = ctx
+	ws.cancel = cancel
+	ws.headPool = gnetwork.NewBufferPool(HeadLen)
+	ws.bodyPool = gnetwork.NewBufferPool(op.MaxIncomingPacket)
+	ws.conns = make(map[*websocket.Conn]struct{})
+	ws.limiter = gnetwork.NewConnLimiter(&op.Limit)
+	ws.server = &http.Server{
+		Handler: websocket.Server{
+			//H5 客户端来源不固定, 不校验 Origin
+			Handshake: func(*websocket.Config, *http.Request) error {
+				return nil
+			},
+			Handler: func(c *websocket.Conn) {
+				ws.handleConn(c, _handle, op)
+			},
+		},
+	}
+	ws.state.Store(TypeWorking)
+
+	go func() {
+		_ = ws.server.Serve(listener)
+	}()
+	return nil
+}
+
+func (ws *WsServer) Addr() string {
+	if ws.listener != nil {
+		return ws.listener.Addr().String()
+	}
+	return ""
+}
+
+// Stop stops the server
+// 具有可重入性且线程安全, 这意味着这个方法可以被并发多次调用，而不会影响程序的状态或者产生不可预期的结果
+func (ws *WsServer) Stop() error {
+	ws.stop(nil)
+	return nil
+}
+
+// stop 参考 network.Server.stop
+func (ws *WsServer) stop(cause error) {
+	if ws.state.CompareAndSwap(TypeWorking, TypeStopped) {
+		if ws.cancel != nil {
+			ws.cancel(cause)
+		}
+		if ws.server != nil {
+			_ = ws.server.Close()
+		}
+	}
+}
+
+// GracefulStop 停止接收新连接并通知所有存活连接关闭, 阻塞直到所有连接的处理协程退出;
+// 参数 ctx 结束时强制关闭剩余连接并返回 ctx.Err()
+func (ws *WsServer) GracefulStop(ctx context.Context) error {
+	ws.stop(gnetwork.ErrServerDraining)
+	ws.mu.Lock()
+	ws.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ws.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		ws.mu.Lock()
+		for c := range ws.conns {
+			_ = c.Close()
+		}
+		ws.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// handleConn 在 websocket 库的处理协程中运行, 返回后 websocket 连接被关闭
+// 接入限流在 WebSocket 握手完成后进行
+func (ws *WsServer) handleConn(c *websocket.Conn, _handle func(conn IConn), op *gnetwork.AcceptorOptions) {
+	generic := &wsConn{Conn: c, remote: wsAddr(c.Request().RemoteAddr)}
+	if !ws.limiter.Acquire(generic) {
+		return
+	}
+	defer ws.limiter.Release(generic)
+
+	ws.mu.Lock()
+	if ws.state.Load() != TypeWorking {
+		ws.mu.Unlock()
+		return
+	}
+	ws.conns[c] = struct{}{}
+	ws.wg.Add(1)
+	ws.mu.Unlock()
+
+	head := ws.headPool.Get().(*gnetwork.Buffer)
+	body := ws.bodyPool.Get().(*gnetwork.Buffer)
+	defer func() {
+		ws.headPool.Put(head)
+		ws.bodyPool.Put(body)
+		ws.mu.Lock()
+		delete(ws.conns, c)
+		ws.mu.Unlock()
+		ws.wg.Done()
+	}()
+
+	c.PayloadType = websocket.BinaryFrame
+	serveConn(ws.ctx, generic, head.Bytes, body.Bytes, op, _handle)
+}
@@ -0,0 +1,136 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/orbit-w/meteor/modules/net/network"
+	packet2 "github.com/orbit-w/meteor/modules/net/packet"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+/*
+   @Author: orbit-w
+   @File: ws_test
+   @2026 10月 周日 16:40
+*/
+
+func Test_WsEcho(t *testing.T) {
+	server := serveWsEcho(t, DefaultServerConfig())
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.WS))
+	defer func() {
+		_ = conn.Close()
+	}()
+	for _, msg := range []string{"hello", "websocket", "world"} {
+		assertEcho(t, conn, msg)
+	}
+}
+
+func Test_WssEcho(t *testing.T) {
+	ca := newTestCA(t)
+	conf := DefaultServerConfig()
+	conf.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server", true)},
+	}
+	server := serveWsEcho(t, conf)
+	defer server.Stop()
+
+	conn := DialWithOps(context.Background(), server.Addr(), WithProtocol(network.WS),
+		WithTLSConfig(&tls.Config{RootCAs: ca.pool}))
+	defer func() {
+		_ = conn.Close()
+	}()
+	assertEcho(t, conn, "hello, wss")
+}
+
+// Test_WsRawClient 模拟 H5 客户端: 直接通过 WebSocket 二进制消息收发 Codec 帧
+func Test_WsRawClient(t *testing.T) {
+	server := serveWsEcho(t, DefaultServerConfig())
+	defer server.Stop()
+
+	ws, err := websocket.Dial("ws://"+server.Addr()+"/", "", "http://localhost/")
+	assert.NoError(t, err)
+	defer func() {
+		_ = ws.Close()
+	}()
+	_ = ws.SetDeadline(time.Now().Add(time.Second * 5))
+	codec := network.NewCodec(MaxIncomingPacket, false, 0)
+
+	//心跳
+	ping := codec.EncodeBody(nil, network.TypeMessageHeartbeat)
+	assert.NoError(t, websocket.Message.Send(ws, ping.Data()))
+	packet2.Return(ping)
+	var frame []byte
+	assert.NoError(t, websocket.Message.Receive(ws, &frame))
+	_, head, err := codec.DecodeDatagram(frame)
+	assert.NoError(t, err)
+	assert.Equal(t, int8(network.TypeMessageHeartbeat), head)
+
+	//业务消息, 消息体为 ControlBuffer 格式的批量消息
+	w := packet2.WriterP(64)
+	w.WriteBytes32([]byte("hello, h5"))
+	pack, err := codec.Encode(w.Data(), network.TypeMessageRaw)
+	assert.NoError(t, err)
+	packet2.Return(w)
+	assert.NoError(t, websocket.Message.Send(ws, pack.Data()))
+	packet2.Return(pack)
+
+	assert.NoError(t, websocket.Message.Receive(ws, &frame))
+	data, head, err := codec.DecodeDatagram(frame)
+	assert.NoError(t, err)
+	assert.Equal(t, int8(network.TypeMessageRaw), head)
+	in, err := packet2.ReaderP(data).ReadBytes32()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello, h5", string(in))
+
+	//服务端积压多个批次时, 每个 WebSocket 消息仍然只承载一帧
+	const count = 2000
+	w = packet2.WriterP(count * 16)
+	for i := 0; i < count; i++ {
+		w.WriteBytes32([]byte(fmt.Sprintf("msg-%d", i)))
+	}
+	pack, err = codec.Encode(w.Data(), network.TypeMessageRaw)
+	assert.NoError(t, err)
+	packet2.Return(w)
+	assert.NoError(t, websocket.Message.Send(ws, pack.Data()))
+	packet2.Return(pack)
+
+	var frames, received int
+	for received < count {
+		assert.NoError(t, websocket.Message.Receive(ws, &frame))
+		data, head, err = codec.DecodeDatagram(frame)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, int8(network.TypeMessageRaw), head)
+		frames++
+		reader := packet2.ReaderP(data)
+		for len(reader.Remain()) > 0 {
+			in, err = reader.ReadBytes32()
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("msg-%d", received), string(in))
+			received++
+		}
+	}
+	assert.Greater(t, frames, 1)
+}
+
+func serveWsEcho(t *testing.T, conf *Config) IServer {
+	server, err := ServeByConfig("ws", "127.0.0.1:0", func(conn IConn) {
+		for {
+			in, err := conn.Recv(context.Background())
+			if err != nil {
+				return
+			}
+			_ = conn.Send(in)
+		}
+	}, conf)
+	assert.NoError(t, err)
+	return server
+}
@@ -75,15 +75,33 @@ func (ins *Unbounded[V]) Send(msg V) error {
 
 // Receive sync consume with flush all
 func (ins *Unbounded[V]) Receive(consumer func(msg V) (exit bool)) {
+	ins.run(func() bool {
+		return ins.consume(consumer)
+	})
+}
+
+// ReceiveBatch 与 Receive 相同, 但每次将当前积压的消息按最多 max 条一批交给 consumer,
+// 用于合并多条消息的 IO. consumer 返回后 msgs 会被复用, 不能持有
+func (ins *Unbounded[V]) ReceiveBatch(max int, consumer func(msgs []V) (exit bool)) {
+	if max <= 0 {
+		max = 1
+	}
+	batch := make([]V, 0, max)
+	ins.run(func() bool {
+		return ins.consumeBatch(batch, consumer)
+	})
+}
+
+func (ins *Unbounded[V]) run(consume func() bool) {
 	defer func() {
 		// safety return
-		ins.flushAll(consumer)
+		ins.flushAll(consume)
 		ins.buffer.Reset()
 		ins.out.Reset()
 		close(ins.ch)
 	}()
 
-	ins.receive(consumer)
+	ins.receive(consume)
 }
 
 func (ins *Unbounded[V]) Close() {
@@ -92,7 +110,7 @@ func (ins *Unbounded[V]) Close() {
 	}
 }
 
-func (ins *Unbounded[V]) receive(consumer func(msg V) bool) {
+func (ins *Unbounded[V]) receive(consume func() bool) {
 LOOP:
 	ins.mu.Lock()
 	for ins.buffer.Length() > 0 {
@@ -103,7 +121,7 @@ LOOP:
 	ins.wait = true
 	ins.mu.Unlock()
 
-	if exit := ins.consume(consumer); exit {
+	if exit := consume(); exit {
 		return
 	}
 
@@ -115,16 +133,16 @@ LOOP:
 	}
 }
 
-func (ins *Unbounded[V]) flushAll(consumer func(msg V) bool) {
+func (ins *Unbounded[V]) flushAll(consume func() bool) {
 	ins.mu.Lock()
 	ins.err = ErrCancel
-	if !ins.buffer.IsEmpty() {
+	for !ins.buffer.IsEmpty() {
 		m, _ := ins.buffer.Pop()
 		ins.out.Push(m)
 	}
 	ins.mu.Unlock()
 
-	ins.consume(consumer)
+	consume()
 }
 
 func (ins *Unbounded[V]) kick() {
@@ -144,3 +162,19 @@ func (ins *Unbounded[V]) consume(consumer func(msg V) bool) (exit bool) {
 	ins.out.Contract()
 	return
 }
+
+func (ins *Unbounded[V]) consumeBatch(batch []V, consumer func(msgs []V) bool) (exit bool) {
+	for ins.out.Length() > 0 {
+		batch = batch[:0]
+		for len(batch) < cap(batch) && ins.out.Length() > 0 {
+			msg, _ := ins.out.Pop()
+			batch = append(batch, msg)
+		}
+		if r := consumer(batch); r {
+			exit = r
+		}
+	}
+	clear(batch[:cap(batch)])
+	ins.out.Contract()
+	return
+}
@@ -0,0 +1,34 @@
+package unbounded
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+   @Author: orbit-w
+   @File: unbounded_test
+   @2026 10月 周日 17:20
+*/
+
+// 消费者退出时积压在 buffer 中的所有消息都应该在 flushAll 中被消费
+func Test_FlushAllOnExit(t *testing.T) {
+	queue := NewUnbounded[int](4)
+	assert.NoError(t, queue.Send(0))
+
+	var got []int
+	queue.Receive(func(msg int) bool {
+		got = append(got, msg)
+		if msg == 0 {
+			for i := 1; i <= 5; i++ {
+				assert.NoError(t, queue.Send(i))
+			}
+			return true
+		}
+		return false
+	})
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, got)
+	assert.ErrorIs(t, queue.Send(6), ErrCancel)
+}
@@ -15,15 +15,18 @@ import (
 type SenderWrapper struct {
 	sender func(body packet.IPacket) error
 	ch     *unbounded.Unbounded[packet.IPacket]
+	done   chan struct{}
 }
 
 func NewSender(sender func(body packet.IPacket) error) *SenderWrapper {
 	ins := &SenderWrapper{
 		sender: sender,
 		ch:     unbounded.NewUnbounded[packet.IPacket](2048),
+		done:   make(chan struct{}),
 	}
 
 	go func() {
+		defer close(ins.done)
 		defer func() {
 			if x := recover(); x != nil {
 				debug.PrintStack()
@@ -39,6 +42,31 @@ func NewSender(sender func(body packet.IPacket) error) *SenderWrapper {
 	return ins
 }
 
+// NewBatchSender 与 NewSender 相同, 但将发送时积压的多个 packet 按最多 maxBatch 个一批交给 sender,
+// 用于合并为一次系统调用写出. sender 返回后 bodies 会被复用, 不能持有
+func NewBatchSender(sender func(bodies []packet.IPacket) error, maxBatch int) *SenderWrapper {
+	ins := &SenderWrapper{
+		ch:   unbounded.NewUnbounded[packet.IPacket](2048),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(ins.done)
+		defer func() {
+			if x := recover(); x != nil {
+				debug.PrintStack()
+			}
+		}()
+
+		ins.ch.ReceiveBatch(maxBatch, func(msgs []packet.IPacket) bool {
+			_ = sender(msgs)
+			return false
+		})
+	}()
+
+	return ins
+}
+
 func (ins *SenderWrapper) Send(data packet.IPacket) error {
 	return ins.ch.Send(data)
 }
@@ -46,3 +74,8 @@ func (ins *SenderWrapper) Send(data packet.IPacket) error {
 func (ins *SenderWrapper) OnClose() {
 	ins.ch.Close()
 }
+
+// Done 在 OnClose 之后所有已入队的数据发送完毕时关闭
+func (ins *SenderWrapper) Done() <-chan struct{} {
+	return ins.done
+}